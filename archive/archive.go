@@ -0,0 +1,259 @@
+// Copyright © 2020 The Stentor Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package archive stores the fragment files of past releases.
+//
+// Each release is archived in a directory named after its version,
+// holding the release's fragment files and a manifest describing the release.
+package archive
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pelletier/go-toml"
+	"github.com/wfscheper/stentor/config"
+	"github.com/wfscheper/stentor/fragment"
+	"github.com/wfscheper/stentor/internal/semver"
+)
+
+// ManifestName is the name of the manifest file in each release directory.
+const ManifestName = "release.toml"
+
+// Manifest describes an archived release.
+type Manifest struct {
+	// Version is the version of the release.
+	Version string `toml:"version"`
+	// PreviousVersion is the version the release was compared against,
+	// or empty for the first release.
+	PreviousVersion string `toml:"previous_version"`
+	// Date is the date of the release, in YYYY-MM-DD format.
+	Date string `toml:"date"`
+	// Sections are the sections configured when the release was made.
+	Sections []config.Section `toml:"sections"`
+	// Fragments are the fragments of the release that were not read from fragment files,
+	// eg. the ones made from Conventional Commits.
+	Fragments []Fragment `toml:"fragments,omitempty"`
+}
+
+// Fragment is a fragment recorded in a manifest.
+type Fragment struct {
+	// Section is the short name of the section of the fragment.
+	Section string `toml:"section"`
+	// Issues are the issues of the fragment.
+	Issues []string `toml:"issues,omitempty"`
+	// PullRequests are the pull requests of the fragment.
+	PullRequests []string `toml:"pull_requests,omitempty"`
+	// Text is the text of the fragment.
+	Text string `toml:"text"`
+	// Source is the type of the source the fragment came from, eg. commits.
+	Source string `toml:"source"`
+	// Ref identifies the fragment within its source, eg. the hash of a commit.
+	Ref string `toml:"ref"`
+}
+
+// NewFragment returns the manifest record of f.
+func NewFragment(f fragment.Fragment) Fragment {
+	issues := f.Issues
+	if len(issues) == 0 && len(f.PullRequests) == 0 && f.Issue != "" {
+		issues = []string{f.Issue}
+	}
+
+	return Fragment{
+		Section:      f.Section,
+		Issues:       issues,
+		PullRequests: f.PullRequests,
+		Text:         f.Text,
+		Source:       f.Provenance.Source,
+		Ref:          f.Provenance.Ref,
+	}
+}
+
+// Fragment returns the fragment recorded by f.
+func (f Fragment) Fragment() fragment.Fragment {
+	frag := fragment.Fragment{
+		Section:      f.Section,
+		Issues:       f.Issues,
+		PullRequests: f.PullRequests,
+		Text:         f.Text,
+		Provenance:   fragment.Provenance{Source: f.Source, Ref: f.Ref},
+	}
+	switch {
+	case len(f.Issues) > 0:
+		frag.Issue = f.Issues[0]
+	case len(f.PullRequests) > 0:
+		frag.Issue = f.PullRequests[0]
+	}
+
+	return frag
+}
+
+// Release is an archived release.
+type Release struct {
+	Manifest
+	// Dir is the directory the release is archived in.
+	Dir string
+	// Files are the paths of the release's fragment files.
+	Files []string
+}
+
+// Read returns the releases archived in dir, newest first.
+//
+// Releases are ordered by date,
+// and releases made on the same date by their semantic version.
+func Read(dir string) ([]Release, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var releases []Release
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		r, err := readRelease(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		releases = append(releases, r)
+	}
+
+	sort.SliceStable(releases, func(i, j int) bool {
+		a, b := releases[i], releases[j]
+		if a.Date != b.Date {
+			return a.Date > b.Date
+		}
+		return compareVersions(a.Version, b.Version) > 0
+	})
+
+	return releases, nil
+}
+
+func readRelease(dir string) (Release, error) {
+	r := Release{Dir: dir}
+
+	data, err := os.ReadFile(filepath.Join(dir, ManifestName))
+	if err != nil {
+		return r, fmt.Errorf("cannot read manifest: %w", err)
+	}
+
+	if err := toml.Unmarshal(data, &r.Manifest); err != nil {
+		return r, fmt.Errorf("cannot parse manifest %s: %w", filepath.Join(dir, ManifestName), err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return r, err
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() && entry.Name() != ManifestName {
+			r.Files = append(r.Files, filepath.Join(dir, entry.Name()))
+		}
+	}
+
+	return r, nil
+}
+
+// compareVersions compares a and b as semantic versions,
+// or as strings if either is not a semantic version.
+func compareVersions(a, b string) int {
+	va, errA := semver.Parse(a)
+	vb, errB := semver.Parse(b)
+	if errA != nil || errB != nil {
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		default:
+			return 0
+		}
+	}
+
+	return va.Compare(vb)
+}
+
+// Check returns an error if the release version cannot be archived in dir,
+// because version is not a valid directory name or the release is already archived.
+//
+// Callers use it to detect a collision before changing anything else.
+func Check(dir, version string) error {
+	if version == "" {
+		return errors.New("empty version")
+	}
+
+	releaseDir := filepath.Join(dir, version)
+	if filepath.Dir(releaseDir) != filepath.Clean(dir) {
+		return fmt.Errorf("invalid version %q: must not contain path separators", version)
+	}
+
+	if _, err := os.Lstat(releaseDir); err == nil {
+		return alreadyArchived(version, releaseDir)
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+func alreadyArchived(version, releaseDir string) error {
+	return fmt.Errorf("release %s is already archived in %s", version, releaseDir)
+}
+
+// Write moves files into the directory for m.Version under dir,
+// and writes m as the manifest of the release.
+// Fragments that are not in files must be recorded in m.Fragments.
+//
+// It is an error if the release is already archived.
+func Write(dir string, m Manifest, files []string) error {
+	if err := Check(dir, m.Version); err != nil {
+		return err
+	}
+
+	releaseDir := filepath.Join(dir, m.Version)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	if err := os.Mkdir(releaseDir, 0755); err != nil {
+		if os.IsExist(err) {
+			return alreadyArchived(m.Version, releaseDir)
+		}
+		return err
+	}
+
+	data, err := toml.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(filepath.Join(releaseDir, ManifestName), bytes.TrimLeft(data, "\n"), 0644); err != nil {
+		return err
+	}
+
+	for _, fn := range files {
+		if err := os.Rename(fn, filepath.Join(releaseDir, filepath.Base(fn))); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,174 @@
+// Copyright © 2020 The Stentor Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archive
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wfscheper/stentor/config"
+	"github.com/wfscheper/stentor/fragment"
+)
+
+func TestWrite(t *testing.T) {
+	tmpdir := t.TempDir()
+	fragmentDir := filepath.Join(tmpdir, ".stentor.d")
+	archiveDir := filepath.Join(fragmentDir, "archive")
+	require.NoError(t, os.Mkdir(fragmentDir, 0755))
+
+	var files []string
+	for _, name := range []string{"1.feature.md", "2.fix.md"} {
+		fn := filepath.Join(fragmentDir, name)
+		require.NoError(t, os.WriteFile(fn, []byte(name), 0600))
+		files = append(files, fn)
+	}
+
+	m := Manifest{
+		Version:         "v0.2.0",
+		PreviousVersion: "v0.1.0",
+		Date:            "2020-01-02",
+		Sections:        []config.Section{{Name: "Features", ShortName: "feature"}},
+	}
+
+	if assert.NoError(t, Write(archiveDir, m, files)) {
+		for _, fn := range files {
+			assert.NoFileExists(t, fn)
+
+			data, err := os.ReadFile(filepath.Join(archiveDir, "v0.2.0", filepath.Base(fn)))
+			if assert.NoError(t, err) {
+				assert.Equal(t, filepath.Base(fn), string(data))
+			}
+		}
+
+		data, err := os.ReadFile(filepath.Join(archiveDir, "v0.2.0", ManifestName))
+		if assert.NoError(t, err) {
+			assert.Equal(t, `date = "2020-01-02"
+previous_version = "v0.1.0"
+version = "v0.2.0"
+
+[[sections]]
+  name = "Features"
+  short_name = "feature"
+`, string(data))
+		}
+	}
+
+	assert.EqualError(t, Write(archiveDir, m, nil),
+		"release v0.2.0 is already archived in "+filepath.Join(archiveDir, "v0.2.0"))
+
+	m.Version = "../v0.3.0"
+	assert.EqualError(t, Write(archiveDir, m, nil), `invalid version "../v0.3.0": must not contain path separators`)
+
+	m.Version = ""
+	assert.EqualError(t, Write(archiveDir, m, nil), "empty version")
+}
+
+func TestRead(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, m := range []Manifest{
+		{Version: "v0.1.0", Date: "2020-01-01"},
+		{Version: "v0.10.0", PreviousVersion: "v0.9.0", Date: "2020-01-03"},
+		{Version: "v0.9.0", PreviousVersion: "v0.1.0", Date: "2020-01-03"},
+	} {
+		fn := filepath.Join(t.TempDir(), "1.fix.md")
+		require.NoError(t, os.WriteFile(fn, []byte("A fix."), 0600))
+		require.NoError(t, Write(dir, m, []string{fn}))
+	}
+
+	// files next to the release directories are ignored
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), nil, 0600))
+
+	if got, err := Read(dir); assert.NoError(t, err) {
+		var versions []string
+		for _, r := range got {
+			versions = append(versions, r.Version)
+			assert.Equal(t, filepath.Join(dir, r.Version), r.Dir)
+			assert.Equal(t, []string{filepath.Join(dir, r.Version, "1.fix.md")}, r.Files)
+		}
+		assert.Equal(t, []string{"v0.10.0", "v0.9.0", "v0.1.0"}, versions)
+		assert.Equal(t, "v0.9.0", got[0].PreviousVersion)
+	}
+
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "v0.11.0"), 0755))
+	_, err := Read(dir)
+	assert.ErrorContains(t, err, "cannot read manifest: ")
+}
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "v0.1.0"), 0755))
+
+	assert.NoError(t, Check(dir, "v0.2.0"))
+	assert.NoError(t, Check(filepath.Join(dir, "missing"), "v0.2.0"))
+	assert.EqualError(t, Check(dir, "v0.1.0"), "release v0.1.0 is already archived in "+filepath.Join(dir, "v0.1.0"))
+	assert.EqualError(t, Check(dir, "../v0.3.0"), `invalid version "../v0.3.0": must not contain path separators`)
+	assert.EqualError(t, Check(dir, ""), "empty version")
+}
+
+func TestFragment(t *testing.T) {
+	tests := []struct {
+		name string
+		f    fragment.Fragment
+		want Fragment
+	}{
+		{
+			"commit",
+			fragment.Fragment{
+				Section:    "fix",
+				Issue:      "2",
+				Issues:     []string{"2", "3"},
+				Text:       "handle empty input",
+				Provenance: fragment.Provenance{Source: fragment.SourceCommits, Ref: "abc123"},
+			},
+			Fragment{Section: "fix", Issues: []string{"2", "3"}, Text: "handle empty input", Source: "commits", Ref: "abc123"},
+		},
+		{
+			"pull request",
+			fragment.Fragment{
+				Section:      "feature",
+				Issue:        "4",
+				PullRequests: []string{"4"},
+				Text:         "The foo feature.",
+				Provenance:   fragment.Provenance{Source: fragment.SourceCommand, Ref: "./fragments"},
+			},
+			Fragment{Section: "feature", PullRequests: []string{"4"}, Text: "The foo feature.", Source: "command", Ref: "./fragments"},
+		},
+		{
+			"orphan",
+			fragment.Fragment{Section: "fix", Text: "A fix.", Provenance: fragment.Provenance{Source: fragment.SourceCommits, Ref: "def456"}},
+			Fragment{Section: "fix", Text: "A fix.", Source: "commits", Ref: "def456"},
+		},
+	}
+
+	dir := t.TempDir()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewFragment(tt.f)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.f, got.Fragment())
+
+			m := Manifest{Version: "v0.1.0", Date: "2020-01-02", Fragments: []Fragment{got}}
+			require.NoError(t, os.RemoveAll(filepath.Join(dir, m.Version)))
+			require.NoError(t, Write(dir, m, nil))
+			if releases, err := Read(dir); assert.NoError(t, err) && assert.Len(t, releases, 1) {
+				assert.Equal(t, []Fragment{tt.want}, releases[0].Fragments)
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+// Copyright © 2020 The Stentor Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/wfscheper/stentor/config"
+	"github.com/wfscheper/stentor/fragment"
+	"github.com/wfscheper/stentor/internal/git"
+)
+
+func (e Exec) runCheck(args []string) int {
+	fs := e.newFlagSet(appName + " check")
+
+	defaultBase := "origin/HEAD"
+	if ref, ok := lookupEnv(e.Env, "GITHUB_BASE_REF"); ok && ref != "" {
+		defaultBase = "origin/" + ref
+	}
+
+	base := fs.String("base", getEnvString(e.Env, "base", defaultBase), "git ref to compare against")
+	committed := fs.Bool(
+		"committed",
+		getEnvBool(e.Env, "committed", false),
+		"only consider fragments committed to HEAD, ignoring the working tree",
+	)
+	skipTrailer := fs.String(
+		"skip-trailer",
+		getEnvString(e.Env, "skip_trailer", "Skip-Changelog"),
+		"skip the check if a commit since base has this trailer",
+	)
+	skipBranch := fs.String(
+		"skip-branch",
+		getEnvString(e.Env, "skip_branch", ""),
+		"skip the check if the branch name matches this pattern",
+	)
+	skipLabel := fs.String(
+		"skip-label",
+		getEnvString(e.Env, "skip_label", "skip-changelog"),
+		"skip the check if the pull request in $GITHUB_EVENT_PATH has this label",
+	)
+
+	e.setUsage(fs, "[OPTIONS]", "Check that a new, valid fragment was added since the base ref.\n\n"+
+		"The check fails if no new fragment file was added to the fragment directory,\n"+
+		"or if any of the new fragment files are invalid.")
+
+	if code, ok := parseCommandFlags(fs, args); !ok {
+		return code
+	}
+
+	if fs.NArg() > 0 {
+		e.err.Println("too many arguments")
+		return genericExitCode
+	}
+
+	cfg, err := e.loadConfig()
+	if err != nil {
+		e.err.Println(err)
+		return genericExitCode
+	}
+
+	repo := git.Repo{Dir: e.WorkDir, Env: e.Env}
+
+	reason, err := e.skipCheck(repo, *base, *skipTrailer, *skipBranch, *skipLabel)
+	if err != nil {
+		e.err.Println(err)
+		return genericExitCode
+	}
+
+	if reason != "" {
+		e.out.Printf("skipping check: %s", reason)
+		return succesfulExitCode
+	}
+
+	// git reports paths relative to the working directory
+	fragmentDir := cfg.FragmentDir
+	if filepath.IsAbs(fragmentDir) {
+		if fragmentDir, err = filepath.Rel(e.WorkDir, fragmentDir); err != nil {
+			e.err.Println(err)
+			return genericExitCode
+		}
+	}
+
+	files, err := repo.AddedFiles(*base, *committed, fragmentDir)
+	if err != nil {
+		e.err.Println(err)
+		return genericExitCode
+	}
+
+	valid, invalid := 0, 0
+	for _, fn := range newFragmentFiles(fragmentDir, cfg.FragmentExtension(), files) {
+		if err := validateFragmentFile(cfg, filepath.Join(e.WorkDir, fn)); err != nil {
+			e.out.Printf("found fragment %s: invalid: %v", fn, err)
+			invalid++
+			continue
+		}
+		e.out.Printf("found fragment %s: valid", fn)
+		valid++
+	}
+
+	switch {
+	case invalid > 0:
+		e.err.Printf("found %d invalid fragment files", invalid)
+		return genericExitCode
+	case valid == 0:
+		e.err.Printf("no new fragment files found in %s since %s", cfg.FragmentDir, *base)
+		return genericExitCode
+	}
+
+	return succesfulExitCode
+}
+
+// skipCheck returns the reason the check should be skipped,
+// or an empty string if it should not be skipped.
+func (e Exec) skipCheck(repo git.Repo, base, trailer, branchPattern, label string) (string, error) {
+	if trailer != "" {
+		commits, err := repo.Log(base + "..HEAD")
+		if err != nil {
+			return "", err
+		}
+
+		for _, c := range commits {
+			for _, t := range c.Trailers() {
+				if strings.EqualFold(t.Key, trailer) {
+					return fmt.Sprintf("commit %.7s has a %s trailer", c.Hash, t.Key), nil
+				}
+			}
+		}
+	}
+
+	if branchPattern != "" {
+		branch, ok := lookupEnv(e.Env, "GITHUB_HEAD_REF")
+		if !ok || branch == "" {
+			var err error
+			if branch, err = repo.CurrentBranch(); err != nil {
+				return "", err
+			}
+		}
+
+		matched, err := path.Match(branchPattern, branch)
+		if err != nil {
+			return "", fmt.Errorf("invalid branch pattern %q: %w", branchPattern, err)
+		}
+		if matched {
+			return fmt.Sprintf("branch %s matches %s", branch, branchPattern), nil
+		}
+	}
+
+	if eventPath, ok := lookupEnv(e.Env, "GITHUB_EVENT_PATH"); ok && eventPath != "" && label != "" {
+		labels, err := readEventLabels(eventPath)
+		if err != nil {
+			return "", err
+		}
+
+		for _, l := range labels {
+			if l == label {
+				return fmt.Sprintf("pull request has the %s label", label), nil
+			}
+		}
+	}
+
+	return "", nil
+}
+
+// newFragmentFiles filters files down to those that look like fragment files,
+// ie. files directly in dir with the extension ext.
+func newFragmentFiles(dir, ext string, files []string) []string {
+	dir = filepath.Clean(dir)
+
+	var fragmentFiles []string
+	for _, fn := range files {
+		fn = filepath.FromSlash(fn)
+		if filepath.Dir(fn) == dir && filepath.Ext(fn) == ext {
+			fragmentFiles = append(fragmentFiles, fn)
+		}
+	}
+
+	return fragmentFiles
+}
+
+// readEventLabels returns the names of the pull request labels
+// in a GitHub Actions event payload.
+func readEventLabels(fn string) ([]string, error) {
+	data, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read event payload: %w", err)
+	}
+
+	var event struct {
+		PullRequest struct {
+			Labels []struct {
+				Name string `json:"name"`
+			} `json:"labels"`
+		} `json:"pull_request"`
+	}
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, fmt.Errorf("cannot parse event payload: %w", err)
+	}
+
+	var labels []string
+	for _, l := range event.PullRequest.Labels {
+		labels = append(labels, l.Name)
+	}
+
+	return labels, nil
+}
+
+// validateFragmentFile returns an error if fn is not a valid fragment
+// for the configured sections.
+func validateFragmentFile(cfg config.Config, fn string) error {
+	f, err := fragment.Parse(fn)
+	if err != nil {
+		return err
+	}
+
+	return verifyFragmentSections(cfg.Sections, []fragment.Fragment{*f})
+}
@@ -0,0 +1,146 @@
+// Copyright © 2020 The Stentor Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wfscheper/stentor/internal/test"
+)
+
+func TestStentor_runCheck(t *testing.T) {
+	tests := []struct {
+		name       string
+		setup      func(g *test.GitRepo)
+		args       []string
+		env        []string
+		wantCode   int
+		wantStdout string
+		wantStderr string
+	}{
+		{
+			name:       "no fragments",
+			setup:      func(g *test.GitRepo) { g.WriteFile("main.go", "package main") },
+			wantCode:   genericExitCode,
+			wantStderr: "stentor: no new fragment files found in .stentor.d since main\n",
+		},
+		{
+			name:       "untracked fragment",
+			setup:      func(g *test.GitRepo) { g.WriteFile(".stentor.d/1.fix.md", "A fix.") },
+			wantStdout: "found fragment .stentor.d/1.fix.md: valid\n",
+		},
+		{
+			name:       "untracked fragment with committed",
+			setup:      func(g *test.GitRepo) { g.WriteFile(".stentor.d/1.fix.md", "A fix.") },
+			args:       []string{"-committed"},
+			wantCode:   genericExitCode,
+			wantStderr: "stentor: no new fragment files found in .stentor.d since main\n",
+		},
+		{
+			name: "committed fragment",
+			setup: func(g *test.GitRepo) {
+				g.WriteFile(".stentor.d/1.fix.md", "A fix.")
+				g.WriteFile(".stentor.d/notes.txt", "not a fragment")
+				g.Commit("add a fragment")
+			},
+			args:       []string{"-committed"},
+			wantStdout: "found fragment .stentor.d/1.fix.md: valid\n",
+		},
+		{
+			name: "invalid fragments",
+			setup: func(g *test.GitRepo) {
+				g.WriteFile(".stentor.d/1.fix.md", "A fix.")
+				g.WriteFile(".stentor.d/2.bug.md", "A bug.")
+				g.WriteFile(".stentor.d/bug.md", "A bug.")
+			},
+			wantCode: genericExitCode,
+			wantStdout: "found fragment .stentor.d/1.fix.md: valid\n" +
+				"found fragment .stentor.d/2.bug.md: invalid: fragment files contained the following invalid" +
+				" section names: \\[bug\\]. section names must be one of the following: \\[security deprecate remove" +
+				" change feature fix\\]\n" +
+				"found fragment .stentor.d/bug.md: invalid: not a valid fragment file: not enough parts\n",
+			wantStderr: "stentor: found 2 invalid fragment files\n",
+		},
+		{
+			name:       "skip trailer",
+			setup:      func(g *test.GitRepo) { g.Commit("docs: fix typo\n\nSkip-Changelog: yes") },
+			wantStdout: "skipping check: commit [0-9a-f]{7} has a Skip-Changelog trailer\n",
+		},
+		{
+			name:       "skip branch",
+			args:       []string{"-skip-branch", "top*"},
+			wantStdout: "skipping check: branch topic matches top\\*\n",
+		},
+		{
+			name:       "skip branch from environment",
+			env:        []string{"GITHUB_HEAD_REF=dependabot/go_modules/foo", "STENTOR_SKIP_BRANCH=dependabot/*/*"},
+			wantStdout: "skipping check: branch dependabot/go_modules/foo matches dependabot/\\*/\\*\n",
+		},
+		{
+			name: "skip label",
+			setup: func(g *test.GitRepo) {
+				g.WriteFile("event.json", `{"pull_request": {"labels": [{"name": "bug"}, {"name": "skip-changelog"}]}}`)
+			},
+			env:        []string{"GITHUB_EVENT_PATH=event.json"},
+			wantStdout: "skipping check: pull request has the skip-changelog label\n",
+		},
+		{
+			name: "label not present",
+			setup: func(g *test.GitRepo) {
+				g.WriteFile("event.json", `{"pull_request": {"labels": [{"name": "bug"}]}}`)
+			},
+			env:        []string{"GITHUB_EVENT_PATH=event.json"},
+			wantCode:   genericExitCode,
+			wantStderr: "stentor: no new fragment files found in .stentor.d since main\n",
+		},
+		{
+			name:       "bad base",
+			args:       []string{"-base", "notexist"},
+			wantCode:   genericExitCode,
+			wantStderr: "stentor: git log: fatal: .*notexist.*\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := test.NewGitRepo(t, t.TempDir())
+			g.WriteFile(".stentor.d/stentor.toml", "[stentor]\nrepository = \"https://github.com/myname/myrepo\"\n")
+			g.Commit("initial commit")
+			g.Git("checkout", "--quiet", "-b", "topic")
+			if tt.setup != nil {
+				tt.setup(g)
+			}
+
+			env := g.Env
+			for _, v := range tt.env {
+				if name, value, ok := strings.Cut(v, "="); ok && name == "GITHUB_EVENT_PATH" {
+					v = name + "=" + filepath.Join(g.Dir, value)
+				}
+				env = append(env, v)
+			}
+
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			s := New(g.Dir, append([]string{appName, "check", "-base", "main"}, tt.args...), env, stderr, stdout)
+
+			assert.Equal(t, tt.wantCode, s.Run())
+			assert.Regexp(t, "^"+tt.wantStdout+"$", stdout.String())
+			assert.Regexp(t, "^"+tt.wantStderr+"$", stderr.String())
+		})
+	}
+}
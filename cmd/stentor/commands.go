@@ -0,0 +1,74 @@
+// Copyright © 2020 The Stentor Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"text/tabwriter"
+)
+
+// command is a stentor subcommand.
+type command struct {
+	// name is the name used to invoke the command.
+	name string
+	// short is a one line description of the command.
+	short string
+	// run runs the command with the remaining command-line arguments,
+	// and returns the exit code.
+	run func(args []string) int
+}
+
+// commands returns the list of stentor subcommands.
+func (e Exec) commands() []command {
+	return []command{
+		{"check", "check that a branch adds a fragment file", e.runCheck},
+		{"init", "set up stentor for a project", e.runInit},
+		{"new", "create a new fragment file", e.runNew},
+		{"next-version", "print the version that follows the previous release", e.runNextVersion},
+		{"rebuild", "regenerate the news file from archived fragments", e.runRebuild},
+		{"release", "update the news file with the pending fragments", e.runReleaseCommand},
+		{"show", "print the notes of a release from the news file", e.runShow},
+		{"templates", "manage the templates used to render releases", e.runTemplates},
+	}
+}
+
+// commandList formats cmds for usage information.
+func commandList(cmds []command) string {
+	var b bytes.Buffer
+	tw := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	for _, cmd := range cmds {
+		fmt.Fprintf(tw, "\t%s\t%s\n", cmd.name, cmd.short)
+	}
+	tw.Flush()
+
+	return b.String()
+}
+
+// parseCommandFlags parses args into fs.
+//
+// It returns the exit code to use and false if the command should stop,
+// either because the user asked for help or because the flags are invalid.
+func parseCommandFlags(fs *flag.FlagSet, args []string) (int, bool) {
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return succesfulExitCode, false
+		}
+		return genericExitCode, false
+	}
+
+	return succesfulExitCode, true
+}
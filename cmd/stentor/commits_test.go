@@ -0,0 +1,153 @@
+// Copyright © 2020 The Stentor Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wfscheper/stentor"
+	"github.com/wfscheper/stentor/archive"
+	"github.com/wfscheper/stentor/internal/test"
+)
+
+func TestStentor_commitFragments(t *testing.T) {
+	g := test.NewGitRepo(t, t.TempDir())
+	g.WriteFile(".stentor.d/stentor.toml", "[stentor]\nrepository = \"https://github.com/myname/myrepo\"\n"+
+		"[stentor.commits]\ntypes = { feat = \"feature\", fix = \"fix\", perf = \"fix\" }\n")
+	g.Commit("initial commit")
+	g.Git("tag", "v1.0.0")
+	g.Commit("fix: handle empty input\n\nRefs: #2")
+	g.Commit("docs: fix a typo")
+	g.Commit("perf: parse twice as fast")
+	g.WriteFile(".stentor.d/1.feature.md", "The foo feature.")
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(g.Dir))
+	defer os.Chdir(wd) // nolint:errcheck // defer func
+
+	run := func(args ...string) (int, string, string) {
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		s := New(g.Dir, append([]string{appName}, args...), append(g.Env, "STENTOR_DATE=2020-01-02"), stderr, stdout)
+		return s.Run(), stdout.String(), stderr.String()
+	}
+
+	code, stdout, stderr := run("v1.1.0", "v1.0.0")
+	if assert.Equal(t, succesfulExitCode, code, stderr) {
+		assert.Equal(t, "## [v1.1.0] - 2020-01-02\n\n"+
+			"### Added\n\n"+
+			"- The foo feature.\n"+
+			"  [#1](https://github.com/myname/myrepo/issues/1)\n\n\n"+
+			"### Fixed\n\n"+
+			"- handle empty input\n"+
+			"  [#2](https://github.com/myname/myrepo/issues/2)\n"+
+			"- parse twice as fast\n\n\n"+
+			"[v1.1.0]: https://github.com/myname/myrepo/compare/v1.0.0...v1.1.0\n\n\n"+
+			"----\n\n", stdout)
+	}
+
+	g.Commit("feat!: drop the v1 api")
+	require.NoError(t, os.Remove(".stentor.d/1.feature.md"))
+
+	code, stdout, stderr = run("next-version")
+	assert.Equal(t, succesfulExitCode, code, stderr)
+	assert.Equal(t, "v2.0.0\n", stdout)
+	assert.Equal(t, "stentor: using PREVIOUS v1.0.0 from git tags\n", stderr)
+
+	code, _, stderr = run("v1.1.0", "notexist")
+	assert.Equal(t, genericExitCode, code)
+	assert.Regexp(t, "^stentor: cannot read commits since notexist: git log: ", stderr)
+}
+
+func TestStentor_firstRelease(t *testing.T) {
+	g := test.NewGitRepo(t, t.TempDir())
+	g.WriteFile(".stentor.d/stentor.toml", "[stentor]\nrepository = \"https://github.com/myname/myrepo\"\n"+
+		"[stentor.commits]\ntypes = { feat = \"feature\" }\n")
+	g.Commit("feat: the first feature")
+	g.Commit("feat: the second feature")
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(g.Dir))
+	defer os.Chdir(wd) // nolint:errcheck // defer func
+
+	run := func(args ...string) (int, string, string) {
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		s := New(g.Dir, append([]string{appName}, args...), append(g.Env, "STENTOR_DATE=2020-01-02"), stderr, stdout)
+		return s.Run(), stdout.String(), stderr.String()
+	}
+
+	code, stdout, stderr := run("v0.1.0")
+	if assert.Equal(t, succesfulExitCode, code, stderr) {
+		assert.Equal(t, "## [v0.1.0] - 2020-01-02\n\n"+
+			"### Added\n\n"+
+			"- the first feature\n"+
+			"- the second feature\n\n\n"+
+			"[v0.1.0]: https://github.com/myname/myrepo/releases/tag/v0.1.0\n\n\n"+
+			"----\n\n", stdout)
+	}
+	assert.Equal(t, "stentor: no PREVIOUS found in git tags, so this is the first release\n", stderr)
+
+	code, _, stderr = run("next-version")
+	assert.Equal(t, genericExitCode, code)
+	assert.Equal(t, "stentor: no PREVIOUS found in git tags, so this is the first release\n"+
+		"stentor: cannot bump PREVIOUS: there is no previous release, so give NEW for the first release\n", stderr)
+}
+
+func TestStentor_rebuildCommitFragments(t *testing.T) {
+	g := test.NewGitRepo(t, t.TempDir())
+	g.WriteFile(".stentor.d/stentor.toml", "[stentor]\nrepository = \"https://github.com/myname/myrepo\"\n"+
+		"archive_dir = \".stentor.d/archive\"\n"+
+		"[stentor.commits]\ntypes = { feat = \"feature\", fix = \"fix\" }\n")
+	g.WriteFile("CHANGELOG.md", "# Changelog\n\n"+stentor.CommentMD+"\n")
+	g.Commit("initial commit")
+	g.Git("tag", "v1.0.0")
+	g.Commit("fix: handle empty input\n\nRefs: #2, #3")
+	g.WriteFile(".stentor.d/1.feature.md", "The foo feature.")
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(g.Dir))
+	defer os.Chdir(wd) // nolint:errcheck // defer func
+
+	run := func(args ...string) (int, string, string) {
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		s := New(g.Dir, append([]string{appName}, args...), append(g.Env, "STENTOR_DATE=2020-01-02"), stderr, stdout)
+		return s.Run(), stdout.String(), stderr.String()
+	}
+
+	code, _, stderr := run("release", "v1.1.0", "v1.0.0")
+	require.Equal(t, succesfulExitCode, code, stderr)
+
+	data, err := os.ReadFile(filepath.Join(".stentor.d", "archive", "v1.1.0", archive.ManifestName))
+	if assert.NoError(t, err) {
+		assert.Contains(t, string(data), "[[fragments]]\n"+
+			"  issues = [\"2\", \"3\"]\n")
+	}
+
+	news, err := os.ReadFile("CHANGELOG.md")
+	require.NoError(t, err)
+	assert.Contains(t, string(news), "- handle empty input\n")
+
+	code, stdout, stderr := run("rebuild")
+	if assert.Equal(t, succesfulExitCode, code, stderr) {
+		assert.Equal(t, string(news), stdout)
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright © 2020 The Stentor Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"path/filepath"
+
+	"github.com/wfscheper/stentor/config"
+	"github.com/wfscheper/stentor/internal/diff"
+	"github.com/wfscheper/stentor/newsfile"
+)
+
+// nothingToReleaseExitCode is returned by a dry run if there are no fragment files.
+const nothingToReleaseExitCode = 2
+
+// dryRunRelease prints the news file with the release data added,
+// or a diff of the changes with -diff,
+// followed by what happens to each of the fragment files.
+func (e Exec) dryRunRelease(cfg config.Config, data []byte, fragmentFiles []string, version string) int {
+	old, updated, err := newsfile.PreviewRelease(cfg.NewsFile, cfg.StartComment(), data, cfg.HeaderTemplate == "")
+	if err != nil {
+		e.err.Printf("cannot update %s: %v", cfg.NewsFile, err)
+		return genericExitCode
+	}
+
+	if *e.diff {
+		name := filepath.ToSlash(cfg.NewsFile)
+		oldName := "a/" + name
+		if old == nil {
+			oldName = "/dev/null"
+		}
+		e.out.Print(diff.Unified(oldName, "b/"+name, old, updated))
+	} else {
+		e.out.Print(string(updated))
+	}
+
+	if fn := e.releaseDataFile(cfg); fn != "" {
+		e.out.Printf("would write %s", fn)
+	}
+
+	for _, fn := range fragmentFiles {
+		if cfg.ArchiveDir != "" {
+			e.out.Printf("would archive %s to %s", fn, filepath.Join(cfg.ArchiveDir, version))
+		} else {
+			e.out.Printf("would remove %s", fn)
+		}
+	}
+
+	return succesfulExitCode
+}
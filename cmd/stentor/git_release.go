@@ -0,0 +1,143 @@
+// Copyright © 2020 The Stentor Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/wfscheper/stentor/config"
+	"github.com/wfscheper/stentor/internal/git"
+	"github.com/wfscheper/stentor/internal/templates"
+	"github.com/wfscheper/stentor/release"
+)
+
+// defaultCommitMessage is the commit message template used if commit_message is not set.
+const defaultCommitMessage = "Release {{ .Version }}"
+
+// gitRelease commits and tags a release.
+type gitRelease struct {
+	repo git.Repo
+	// paths are the files changed by the release.
+	paths []string
+	// message is the commit message.
+	message string
+	// tag is the name of the tag.
+	tag string
+	// notes is the message of the tag.
+	notes string
+}
+
+// prepareGitRelease returns a gitRelease that commits the changes made by releasing r.
+//
+// It must be called before the news file and fragments are changed,
+// as it refuses to commit if there are changes to any other files,
+// and it renders the commit and tag messages up front,
+// so that template errors are reported before anything changes.
+func (e Exec) prepareGitRelease(cfg config.Config, r *release.Release, fragmentFiles []string) (*gitRelease, error) {
+	gr := &gitRelease{repo: git.Repo{Dir: e.WorkDir, Env: e.Env}, tag: r.Version}
+
+	if *e.gitTag {
+		exists, err := gr.repo.TagExists(r.Version)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, fmt.Errorf("cannot tag release: tag %s already exists", r.Version)
+		}
+	}
+
+	// the files stentor changes, to exclude from the check for unrelated changes
+	changes := append([]string{cfg.NewsFile}, fragmentFiles...)
+	if cfg.ArchiveDir != "" {
+		changes = append(changes, cfg.ArchiveDir)
+	}
+	if fn := e.releaseDataFile(cfg); fn != "" {
+		changes = append(changes, fn)
+	}
+
+	pathspecs := []string{":/"}
+	for _, p := range changes {
+		pathspecs = append(pathspecs, ":(exclude,literal)"+filepath.ToSlash(p))
+	}
+
+	unrelated, err := gr.repo.Status(pathspecs...)
+	if err != nil {
+		return nil, err
+	}
+	if len(unrelated) > 0 {
+		return nil, fmt.Errorf("cannot commit release: working tree has unrelated changes: %s",
+			strings.Join(unrelated, ", "))
+	}
+
+	// untracked fragment files are removed without a trace,
+	// and git refuses to stage paths it does not know about
+	tracked, err := gr.repo.TrackedFiles(fragmentFiles...)
+	if err != nil {
+		return nil, err
+	}
+
+	gr.paths = append([]string{cfg.NewsFile}, tracked...)
+	if cfg.ArchiveDir != "" {
+		gr.paths = append(gr.paths, cfg.ArchiveDir)
+	}
+	if fn := e.releaseDataFile(cfg); fn != "" {
+		gr.paths = append(gr.paths, fn)
+	}
+
+	message := cfg.CommitMessage
+	if message == "" {
+		message = defaultCommitMessage
+	}
+
+	tmpl, err := templates.ParseText("commit_message", message)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse commit message template: %w", err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, r); err != nil {
+		return nil, fmt.Errorf("cannot render commit message template: %w", err)
+	}
+	gr.message = buf.String()
+
+	buf.Reset()
+	if err := generateSections(buf, cfg, r); err != nil {
+		return nil, err
+	}
+	gr.notes = buf.String()
+
+	return gr, nil
+}
+
+// commit stages and commits the changes made by the release,
+// and tags the commit if tag is true.
+func (gr *gitRelease) commit(tag bool) error {
+	if err := gr.repo.Add(gr.paths...); err != nil {
+		return err
+	}
+
+	if err := gr.repo.CommitStaged(gr.message); err != nil {
+		return err
+	}
+
+	if tag {
+		return gr.repo.Tag(gr.tag, gr.notes)
+	}
+
+	return nil
+}
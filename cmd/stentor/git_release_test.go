@@ -0,0 +1,170 @@
+// Copyright © 2020 The Stentor Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wfscheper/stentor/internal/test"
+)
+
+func TestStentor_gitRelease(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     string
+		setup      func(g *test.GitRepo)
+		args       []string
+		wantCode   int
+		wantStderr string
+		// check runs after a successful release
+		check func(t *testing.T, g *test.GitRepo)
+	}{
+		{
+			name: "commit",
+			setup: func(g *test.GitRepo) {
+				g.WriteFile(".stentor.d/1.feature.md", "A feature.")
+				g.Commit("add a feature")
+				g.WriteFile(".stentor.d/2.fix.md", "An uncommitted fix.")
+			},
+			args: []string{"release", "-git-commit", "v0.2.0", "v0.1.0"},
+			check: func(t *testing.T, g *test.GitRepo) {
+				assert.Equal(t, "Release v0.2.0", g.Git("log", "-1", "--format=%s"))
+				assert.Equal(t, "D\t.stentor.d/1.feature.md\nM\tCHANGELOG.md", g.Git("show", "--name-status", "--format=", "HEAD"))
+				assert.Empty(t, g.Git("tag", "--list"))
+			},
+		},
+		{
+			name: "tag",
+			config: `commit_message = """
+chore(release): {{ .Version }}
+
+Released on {{ .Date.Format "2006-01-02" }}."""
+`,
+			setup: func(g *test.GitRepo) {
+				g.WriteFile(".stentor.d/1.feature.md", "A feature.")
+			},
+			args: []string{"-release", "-git-tag", "v0.2.0", "v0.1.0"},
+			check: func(t *testing.T, g *test.GitRepo) {
+				assert.Equal(t, "chore(release): v0.2.0\n\nReleased on 2020-01-02.", g.Git("log", "-1", "--format=%B"))
+				assert.Equal(t, "M\tCHANGELOG.md", g.Git("show", "--name-status", "--format=", "HEAD"))
+				assert.Equal(t, g.Git("rev-parse", "HEAD"), g.Git("rev-parse", "v0.2.0^{commit}"))
+				assert.Contains(t, g.Git("tag", "--list", "--format=%(contents)", "v0.2.0"), "### Added\n\n- A feature.")
+			},
+		},
+		{
+			name:   "archive",
+			config: "archive_dir = \".stentor.d/archive\"\n",
+			setup: func(g *test.GitRepo) {
+				g.WriteFile(".stentor.d/1.feature.md", "A feature.")
+				g.Commit("add a feature")
+			},
+			args: []string{"release", "-git-commit", "v0.2.0", "v0.1.0"},
+			check: func(t *testing.T, g *test.GitRepo) {
+				assert.Equal(t,
+					"D\t.stentor.d/1.feature.md\nA\t.stentor.d/archive/v0.2.0/1.feature.md\n"+
+						"A\t.stentor.d/archive/v0.2.0/release.toml\nM\tCHANGELOG.md",
+					g.Git("show", "--name-status", "--no-renames", "--format=", "HEAD"))
+			},
+		},
+		{
+			name: "format",
+			setup: func(g *test.GitRepo) {
+				g.WriteFile(".stentor.d/1.feature.md", "A feature.")
+				g.Commit("add a feature")
+			},
+			args: []string{"release", "-format", "yaml", "-git-commit", "v0.2.0", "v0.1.0"},
+			check: func(t *testing.T, g *test.GitRepo) {
+				assert.Equal(t, "D\t.stentor.d/1.feature.md\nM\tCHANGELOG.md\nA\tCHANGELOG.yaml",
+					g.Git("show", "--name-status", "--format=", "HEAD"))
+			},
+		},
+		{
+			name: "unrelated changes",
+			setup: func(g *test.GitRepo) {
+				g.WriteFile(".stentor.d/1.feature.md", "A feature.")
+				g.WriteFile("main.go", "package main")
+			},
+			args:       []string{"release", "-git-commit", "v0.2.0", "v0.1.0"},
+			wantCode:   genericExitCode,
+			wantStderr: "stentor: cannot commit release: working tree has unrelated changes: main.go\n",
+		},
+		{
+			name: "without release",
+			setup: func(g *test.GitRepo) {
+				g.WriteFile(".stentor.d/1.feature.md", "A feature.")
+			},
+			args:       []string{"-git-commit", "v0.2.0", "v0.1.0"},
+			wantCode:   genericExitCode,
+			wantStderr: "stentor: -git-commit and -git-tag require -release\n",
+		},
+		{
+			name: "dry run",
+			setup: func(g *test.GitRepo) {
+				g.WriteFile(".stentor.d/1.feature.md", "A feature.")
+			},
+			args:       []string{"release", "-dry-run", "-git-commit", "v0.2.0", "v0.1.0"},
+			wantCode:   genericExitCode,
+			wantStderr: "stentor: -git-commit and -git-tag cannot be used with -dry-run or -diff\n",
+		},
+		{
+			name: "tag exists",
+			setup: func(g *test.GitRepo) {
+				g.Git("tag", "v0.2.0")
+				g.WriteFile(".stentor.d/1.feature.md", "A feature.")
+				g.Commit("add a feature")
+			},
+			args:       []string{"release", "-git-tag", "v0.2.0", "v0.1.0"},
+			wantCode:   genericExitCode,
+			wantStderr: "stentor: cannot tag release: tag v0.2.0 already exists\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := test.NewGitRepo(t, t.TempDir())
+			g.WriteFile(".stentor.d/stentor.toml",
+				"[stentor]\nrepository = \"https://github.com/myname/myrepo\"\n"+tt.config)
+			g.WriteFile("CHANGELOG.md", "# Changelog\n\n<!-- stentor output starts -->\n")
+			g.Commit("initial commit")
+			if tt.setup != nil {
+				tt.setup(g)
+			}
+
+			wd, err := os.Getwd()
+			require.NoError(t, err)
+			require.NoError(t, os.Chdir(g.Dir))
+			defer os.Chdir(wd) // nolint:errcheck // defer func
+
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			s := New(g.Dir, append([]string{appName}, tt.args...), append(g.Env, "STENTOR_DATE=2020-01-02"), stderr, stdout)
+
+			head := g.Git("rev-parse", "HEAD")
+			if assert.Equal(t, tt.wantCode, s.Run(), stderr.String()) && tt.check != nil {
+				assert.Empty(t, g.Git("status", "--porcelain"))
+				tt.check(t, g)
+			}
+			if tt.wantCode != succesfulExitCode {
+				// a failed release leaves the repository alone
+				assert.Equal(t, head, g.Git("rev-parse", "HEAD"))
+				assert.Empty(t, g.Git("diff", "HEAD", "--", "CHANGELOG.md"))
+			}
+			assert.Equal(t, tt.wantStderr, stderr.String())
+		})
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright © 2020 The Stentor Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/wfscheper/stentor"
+	"github.com/wfscheper/stentor/config"
+	"github.com/wfscheper/stentor/internal/git"
+	"github.com/wfscheper/stentor/newsfile"
+)
+
+// newsFileIntros are the introductions written to a new news file by the init command.
+var newsFileIntros = map[string]string{
+	stentor.MarkupAsciiDoc: `= Changelog
+
+All notable changes to this project will be documented in this file.
+
+The format is based on https://keepachangelog.com/en/1.0.0/[Keep a Changelog],
+and this project adheres to https://semver.org/spec/v2.0.0.html[Semantic Versioning].
+
+`,
+	stentor.MarkupMD: `# Changelog
+
+All notable changes to this project will be documented in this file.
+
+The format is based on [Keep a Changelog](https://keepachangelog.com/en/1.0.0/),
+and this project adheres to [Semantic Versioning](https://semver.org/spec/v2.0.0.html).
+
+`,
+	stentor.MarkupRST: "=========\n" +
+		"Changelog\n" +
+		"=========\n" +
+		"\n" +
+		"All notable changes to this project will be documented in this file.\n" +
+		"\n" +
+		"The format is based on `Keep a Changelog <https://keepachangelog.com/en/1.0.0/>`_,\n" +
+		"and this project adheres to `Semantic Versioning <https://semver.org/spec/v2.0.0.html>`_.\n" +
+		"\n",
+}
+
+func (e Exec) runInit(args []string) int {
+	fs := e.newFlagSet(appName + " init")
+
+	repository := fs.String(
+		"repository",
+		getEnvString(e.Env, "repository", ""),
+		"URL of the repository (default: inferred from the origin remote)",
+	)
+	hosting := fs.String("hosting", getEnvString(e.Env, "hosting", stentor.HostingGithub), "repository host")
+	markup := fs.String("markup", getEnvString(e.Env, "markup", stentor.MarkupMD), "markup of the news file")
+	gitkeep := fs.Bool(
+		"gitkeep",
+		getEnvBool(e.Env, "gitkeep", false),
+		"add a .gitkeep file so the fragment directory is kept when empty",
+	)
+
+	e.setUsage(fs, "[OPTIONS]", "Set up stentor for a project.\n\n"+
+		"Writes the config file, and creates the news file,\n"+
+		"or adds the start comment to an existing news file.")
+
+	if code, ok := parseCommandFlags(fs, args); !ok {
+		return code
+	}
+
+	if fs.NArg() > 0 {
+		e.err.Println("too many arguments")
+		return genericExitCode
+	}
+
+	configFile := e.workPath(*e.configFile)
+
+	if _, err := os.Stat(configFile); err == nil {
+		e.err.Printf("%s already exists", *e.configFile)
+		return genericExitCode
+	}
+
+	if *repository == "" {
+		repo, err := originRepository(git.Repo{Dir: e.WorkDir, Env: e.Env})
+		if err != nil {
+			e.err.Printf("cannot determine repository, use the -repository flag: %v", err)
+			return genericExitCode
+		}
+		*repository = repo
+	}
+
+	data, cfg, err := newConfig(*repository, *hosting, *markup)
+	if err != nil {
+		e.err.Println(err)
+		return genericExitCode
+	}
+
+	if err := os.MkdirAll(filepath.Dir(configFile), 0755); err != nil {
+		e.err.Println(err)
+		return genericExitCode
+	}
+
+	if err := os.WriteFile(configFile, data, 0644); err != nil {
+		e.err.Printf("cannot write config file: %v", err)
+		return genericExitCode
+	}
+	e.out.Printf("created %s", *e.configFile)
+
+	msg, err := initNewsFile(e.workPath(cfg.NewsFile), cfg)
+	if err != nil {
+		e.err.Printf("cannot update %s: %v", cfg.NewsFile, err)
+		return genericExitCode
+	}
+	e.out.Println(msg)
+
+	if *gitkeep {
+		fragmentDir := e.workPath(cfg.FragmentDir)
+		if err := os.MkdirAll(fragmentDir, 0755); err != nil {
+			e.err.Println(err)
+			return genericExitCode
+		}
+
+		if err := os.WriteFile(filepath.Join(fragmentDir, ".gitkeep"), nil, 0644); err != nil {
+			e.err.Println(err)
+			return genericExitCode
+		}
+		e.out.Printf("created %s", filepath.Join(cfg.FragmentDir, ".gitkeep"))
+	}
+
+	return succesfulExitCode
+}
+
+// initNewsFile creates the news file fn for cfg,
+// or adds the start comment to it if it already exists.
+//
+// It returns a message describing what was done.
+func initNewsFile(fn string, cfg config.Config) (string, error) {
+	data, err := os.ReadFile(fn)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+
+		data := newsFileIntros[cfg.Markup] + cfg.StartComment()
+		if err := os.WriteFile(fn, []byte(data+"\n"), 0644); err != nil {
+			return "", err
+		}
+		return "created " + cfg.NewsFile, nil
+	}
+
+	updated := newsfile.AddStartComment(data, cfg.StartComment(), cfg.Markup)
+	if len(updated) == len(data) {
+		return cfg.NewsFile + " already has a start comment", nil
+	}
+
+	if err := os.WriteFile(fn, updated, 0644); err != nil {
+		return "", err
+	}
+
+	return "added start comment to " + cfg.NewsFile, nil
+}
+
+// newConfig returns the toml encoded config file for a project,
+// and the parsed config with defaults filled in.
+func newConfig(repository, hosting, markup string) ([]byte, config.Config, error) {
+	data, err := config.Marshal(config.Config{
+		Hosting:    hosting,
+		Markup:     markup,
+		Repository: repository,
+	})
+	if err != nil {
+		return nil, config.Config{}, err
+	}
+
+	cfg, err := config.ParseBytes(data)
+	if err != nil {
+		return nil, cfg, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	if err := config.ValidateConfig(cfg); err != nil {
+		return nil, cfg, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return data, cfg, nil
+}
+
+// originRepository returns the web URL of the repository's origin remote.
+func originRepository(repo git.Repo) (string, error) {
+	remote, err := repo.RemoteURL("origin")
+	if err != nil {
+		return "", err
+	}
+
+	return git.BrowseURL(remote)
+}
@@ -0,0 +1,63 @@
+// Copyright © 2020 The Stentor Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wfscheper/stentor/internal/test"
+)
+
+func TestStentor_runInit_origin(t *testing.T) {
+	g := test.NewGitRepo(t, t.TempDir())
+	g.Git("remote", "add", "origin", "git@gitlab.com:mygroup/myrepo.git")
+
+	// news files are relative to the current directory
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(g.Dir))
+	defer os.Chdir(wd) // nolint:errcheck // defer func
+
+	out := &bytes.Buffer{}
+	s := New(g.Dir, []string{appName, "init", "-hosting", "gitlab"}, g.Env, io.Discard, out)
+	require.Equal(t, succesfulExitCode, s.Run())
+
+	data, err := os.ReadFile(filepath.Join(g.Dir, ".stentor.d", "stentor.toml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `repository = "https://gitlab.com/mygroup/myrepo"`)
+	assert.Equal(t, "created .stentor.d/stentor.toml\ncreated CHANGELOG.md\n", out.String())
+}
+
+func TestStentor_runInit_gitkeep(t *testing.T) {
+	// the config file is outside the fragment directory, so init does not create it,
+	// and the working directory is not the current directory
+	dir := t.TempDir()
+
+	out := &bytes.Buffer{}
+	s := New(dir, []string{appName, "init", "-config", "stentor.toml", "-gitkeep",
+		"-repository", "https://github.com/myname/myrepo"}, os.Environ(), io.Discard, out)
+	require.Equal(t, succesfulExitCode, s.Run())
+
+	for _, fn := range []string{"stentor.toml", "CHANGELOG.md", filepath.Join(".stentor.d", ".gitkeep")} {
+		assert.FileExists(t, filepath.Join(dir, fn))
+	}
+	assert.Equal(t, "created stentor.toml\ncreated CHANGELOG.md\ncreated .stentor.d/.gitkeep\n", out.String())
+}
@@ -124,6 +124,7 @@ func runTest(name, relPath, wd string, run test.RunFunc) func(t *testing.T) {
 		} else {
 			testCase.CompareError(err, testEnv.GetStderr())
 			testCase.CompareOutput(testEnv.GetStdout())
+			testCase.CompareFinal(testEnv.Join())
 		}
 	}
 }
@@ -0,0 +1,195 @@
+// Copyright © 2020 The Stentor Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/wfscheper/stentor/config"
+	"github.com/wfscheper/stentor/fragment"
+)
+
+func (e Exec) runNew(args []string) int {
+	fs := e.newFlagSet(appName + " new")
+
+	sectionName := fs.String("section", getEnvString(e.Env, "section", ""), "section of the new fragment")
+	issue := fs.String("issue", getEnvString(e.Env, "issue", ""), "issue the fragment refers to")
+	orphan := fs.Bool("orphan", getEnvBool(e.Env, "orphan", false),
+		"create an orphan fragment, which refers to no issue and is named after the summary")
+	summary := fs.String("summary", getEnvString(e.Env, "summary", ""), "short summary of the change")
+	edit := fs.Bool("edit", getEnvBool(e.Env, "edit", false), "open the new fragment with $EDITOR")
+
+	e.setUsage(fs, "[OPTIONS]", "Create a new fragment file in the fragment directory.\n\n"+
+		"The file is named after the issue, section, and summary,\n"+
+		"and is seeded with the summary and the section's skeleton text.")
+
+	if code, ok := parseCommandFlags(fs, args); !ok {
+		return code
+	}
+
+	switch {
+	case fs.NArg() > 0:
+		e.err.Println("too many arguments")
+		return genericExitCode
+	case *sectionName == "":
+		e.err.Println("missing -section flag")
+		return genericExitCode
+	case *orphan && *issue != "":
+		e.err.Println("-orphan and -issue are mutually exclusive")
+		return genericExitCode
+	case *orphan && *summary == "":
+		e.err.Println("missing -summary flag, required by -orphan")
+		return genericExitCode
+	case !*orphan && *issue == "":
+		e.err.Println("missing -issue flag")
+		return genericExitCode
+	}
+
+	cfg, err := e.loadConfig()
+	if err != nil {
+		e.err.Println(err)
+		return genericExitCode
+	}
+
+	sec, err := findSection(cfg.Sections, *sectionName)
+	if err != nil {
+		e.err.Println(err)
+		return genericExitCode
+	}
+
+	var name string
+	if *orphan {
+		name, err = fragment.OrphanFilename(sec.ShortName, *summary, cfg.FragmentExtension())
+	} else {
+		name, err = fragment.Filename(*issue, sec.ShortName, *summary, cfg.FragmentExtension())
+	}
+	if err != nil {
+		e.err.Printf("cannot create fragment: %v", err)
+		return genericExitCode
+	}
+
+	fn := filepath.Join(cfg.FragmentDir, name)
+	if err := writeNewFragment(fn, fragmentContents(*summary, sec.Skeleton)); err != nil {
+		e.err.Printf("cannot create fragment: %v", err)
+		return genericExitCode
+	}
+
+	if *edit {
+		if err := e.editFragment(fn); err != nil {
+			e.err.Printf("cannot edit fragment %s: %v", fn, err)
+			return genericExitCode
+		}
+	}
+
+	e.out.Println(fn)
+	return succesfulExitCode
+}
+
+// editFragment opens fn in the user's editor.
+//
+// If the user leaves the fragment empty, the file is removed.
+func (e Exec) editFragment(fn string) error {
+	editor, ok := lookupEnv(e.Env, "VISUAL")
+	if !ok || editor == "" {
+		editor, _ = lookupEnv(e.Env, "EDITOR")
+	}
+
+	args := strings.Fields(editor)
+	if len(args) == 0 {
+		return errors.New("neither $VISUAL nor $EDITOR is set")
+	}
+
+	cmd := exec.Command(args[0], append(args[1:], fn)...)
+	cmd.Env = e.Env
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = e.out.Writer()
+	cmd.Stderr = e.err.Writer()
+	if err := cmd.Run(); err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(fn)
+	if err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(string(data)) == "" {
+		if err := os.Remove(fn); err != nil {
+			return err
+		}
+		return errors.New("fragment is empty, aborting")
+	}
+
+	return nil
+}
+
+// findSection returns the section with the short name name.
+func findSection(sections []config.Section, name string) (config.Section, error) {
+	validSections := []string{}
+	for _, section := range sections {
+		if section.ShortName == name {
+			return section, nil
+		}
+		validSections = append(validSections, section.ShortName)
+	}
+
+	return config.Section{}, fmt.Errorf("invalid section name: %s."+
+		" section names must be one of the following: %v", name, validSections)
+}
+
+// fragmentContents returns the initial text of a new fragment.
+func fragmentContents(summary, skeleton string) []byte {
+	var paragraphs []string
+	for _, s := range []string{summary, skeleton} {
+		if s = strings.TrimSpace(s); s != "" {
+			paragraphs = append(paragraphs, s)
+		}
+	}
+
+	if len(paragraphs) == 0 {
+		return nil
+	}
+
+	return []byte(strings.Join(paragraphs, "\n\n") + "\n")
+}
+
+// writeNewFragment writes data to fn, creating any missing parent directories.
+//
+// It refuses to overwrite an existing file.
+func writeNewFragment(fn string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(fn), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(fn, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		if os.IsExist(err) {
+			return fmt.Errorf("%s already exists", fn)
+		}
+		return err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,130 @@
+// Copyright © 2020 The Stentor Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/wfscheper/stentor/config"
+	"github.com/wfscheper/stentor/fragment"
+	"github.com/wfscheper/stentor/internal/semver"
+)
+
+// bumpAuto picks the bump level from the sections of the pending fragments.
+const bumpAuto = "auto"
+
+func (e Exec) runNextVersion(args []string) int {
+	fs := e.newFlagSet(appName + " next-version")
+
+	e.releaseFlags(fs, bumpAuto)
+
+	e.setUsage(fs, "[OPTIONS] [PREVIOUS]",
+		"Print the version that follows PREVIOUS, based on the pending fragments.")
+
+	if code, ok := parseCommandFlags(fs, args); !ok {
+		return code
+	}
+
+	if fs.NArg() > 1 {
+		e.err.Println("too many arguments")
+		return genericExitCode
+	}
+
+	cfg, err := e.loadConfig()
+	if err != nil {
+		e.err.Println(err)
+		return genericExitCode
+	}
+
+	previous := fs.Arg(0)
+	if previous == "" {
+		if previous, err = e.detectPrevious(cfg, ""); err != nil {
+			e.err.Println(err)
+			return genericExitCode
+		}
+	}
+
+	_, fragments, err := e.collectFragments(cfg, previous)
+	if err != nil {
+		e.err.Println(err)
+		return genericExitCode
+	}
+
+	next, err := nextVersion(cfg.Sections, fragments, previous, *e.bump)
+	if err != nil {
+		e.err.Println(err)
+		return genericExitCode
+	}
+
+	e.out.Println(next)
+	return succesfulExitCode
+}
+
+// nextVersion returns the version that follows previous.
+//
+// If bump is bumpAuto, the bump level is the highest level of the sections of fragments.
+// Otherwise, it is the level named by bump.
+func nextVersion(sections []config.Section, fragments []fragment.Fragment, previous, bump string) (string, error) {
+	if previous == "" {
+		return "", errors.New("cannot bump PREVIOUS: there is no previous release, so give NEW for the first release")
+	}
+
+	v, err := semver.Parse(previous)
+	if err != nil {
+		return "", fmt.Errorf("cannot bump PREVIOUS: %w", err)
+	}
+
+	var level semver.Level
+	if bump == bumpAuto {
+		if level, err = fragmentsLevel(sections, fragments); err != nil {
+			return "", err
+		}
+	} else if level, err = semver.ParseLevel(bump); err != nil {
+		return "", fmt.Errorf("invalid bump %q: must be one of auto, major, minor, or patch", bump)
+	}
+
+	return v.Bump(level).String(), nil
+}
+
+// fragmentsLevel returns the highest bump level of the sections of fragments.
+//
+// Sections without a bump level cause a patch bump.
+func fragmentsLevel(sections []config.Section, fragments []fragment.Fragment) (semver.Level, error) {
+	if len(fragments) == 0 {
+		return 0, errors.New("no fragments found to choose the version bump from")
+	}
+
+	levels := map[string]semver.Level{}
+	for _, s := range sections {
+		levels[s.ShortName] = semver.Patch
+		if s.Bump != "" {
+			l, err := semver.ParseLevel(s.Bump)
+			if err != nil {
+				return 0, fmt.Errorf("invalid section %s: %w", s.ShortName, err)
+			}
+			levels[s.ShortName] = l
+		}
+	}
+
+	var level semver.Level
+	for _, f := range fragments {
+		if l := levels[f.Section]; l > level {
+			level = l
+		}
+	}
+
+	return level, nil
+}
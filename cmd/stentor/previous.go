@@ -0,0 +1,156 @@
+// Copyright © 2020 The Stentor Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/wfscheper/stentor/config"
+	"github.com/wfscheper/stentor/internal/git"
+	"github.com/wfscheper/stentor/internal/semver"
+	"github.com/wfscheper/stentor/newsfile"
+)
+
+// Sources of the previous version.
+const (
+	previousFromAuto = "auto"
+	previousFromGit  = "git"
+	previousFromNews = "news"
+)
+
+var previousSources = []string{previousFromAuto, previousFromNews, previousFromGit}
+
+// detectPrevious returns the version released before version, using the source set by the -previous-from flag.
+//
+// The version found is logged, so users can see which one is used.
+// If there is no previous version, this is the first release,
+// and detectPrevious returns an empty string.
+func (e Exec) detectPrevious(cfg config.Config, version string) (string, error) {
+	previous, source, err := detectPrevious(cfg, git.Repo{Dir: e.WorkDir, Env: e.Env}, *e.previousFrom, version)
+	if err != nil {
+		return "", err
+	}
+
+	if previous == "" {
+		e.err.Printf("no PREVIOUS found in %s, so this is the first release", source)
+	} else {
+		e.err.Printf("using PREVIOUS %s from %s", previous, source)
+	}
+	return previous, nil
+}
+
+// detectPrevious returns the version released before version, and where it was found.
+//
+// With previousFromNews, the previous version is the most recent release in the news file,
+// or an empty string if it has none.
+// With previousFromGit, it is the highest semantic version tag reachable from HEAD,
+// or an empty string if there are no such tags.
+// With previousFromAuto, the news file is tried before git.
+func detectPrevious(cfg config.Config, repo git.Repo, from, version string) (string, string, error) {
+	switch from {
+	case previousFromNews:
+		previous, err := newsPrevious(cfg, version)
+		if err != nil {
+			return "", "", err
+		}
+		return previous, cfg.NewsFile, nil
+	case previousFromGit:
+		return gitPrevious(repo, version)
+	case previousFromAuto:
+		// a news file without releases may be new, so git tags still have a say
+		if previous, err := newsPrevious(cfg, version); err == nil && previous != "" {
+			return previous, cfg.NewsFile, nil
+		}
+
+		previous, source, err := gitPrevious(repo, version)
+		if err != nil {
+			return "", "", fmt.Errorf("cannot find PREVIOUS in %s or git tags: %w", cfg.NewsFile, err)
+		}
+		return previous, source, nil
+	default:
+		return "", "", fmt.Errorf("invalid previous source %q: must be one of %v", from, previousSources)
+	}
+}
+
+// newsPrevious returns the most recent release in the news file that is not version.
+//
+// If the news file has no other releases, this is the first release,
+// and newsPrevious returns an empty string.
+func newsPrevious(cfg config.Config, version string) (string, error) {
+	data, err := os.ReadFile(cfg.NewsFile)
+	if err != nil {
+		return "", err
+	}
+
+	entries, err := newsfile.ParseEntries(data, cfg.StartComment(), cfg.Markup)
+	if err != nil {
+		return "", fmt.Errorf("cannot read %s: %w", cfg.NewsFile, err)
+	}
+
+	for _, entry := range entries {
+		if entry.Version != "" && !sameVersion(entry.Version, version) {
+			return entry.Version, nil
+		}
+	}
+
+	return "", nil
+}
+
+// gitPrevious returns the highest semantic version tag reachable from HEAD that is not version.
+//
+// If there are no such tags, or there is no HEAD because there is no repository or no commits yet,
+// this is the first release, and gitPrevious returns an empty string instead.
+func gitPrevious(repo git.Repo, version string) (string, string, error) {
+	hasHead, err := repo.HasHead()
+	if err != nil {
+		return "", "", err
+	}
+	if !hasHead {
+		return "", "git tags", nil
+	}
+
+	tags, err := repo.Tags("HEAD")
+	if err != nil {
+		return "", "", err
+	}
+
+	var (
+		highest semver.Version
+		found   bool
+	)
+	for _, tag := range tags {
+		v, err := semver.Parse(tag)
+		if err != nil || sameVersion(tag, version) {
+			continue
+		}
+
+		if !found || v.Compare(highest) > 0 {
+			highest, found = v, true
+		}
+	}
+
+	if !found {
+		return "", "git tags", nil
+	}
+
+	return highest.String(), "git tags", nil
+}
+
+// sameVersion reports whether a and b are the same version, with or without a leading "v".
+func sameVersion(a, b string) bool {
+	return strings.TrimPrefix(a, "v") == strings.TrimPrefix(b, "v")
+}
@@ -0,0 +1,208 @@
+// Copyright © 2020 The Stentor Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wfscheper/stentor/config"
+	"github.com/wfscheper/stentor/internal/git"
+	"github.com/wfscheper/stentor/internal/test"
+)
+
+func Test_detectPrevious(t *testing.T) {
+	const changelog = "# Changelog\n\n<!-- stentor output starts -->\n\n" +
+		"## [v0.3.0] - 2020-01-03\n\nNo significant changes.\n\n\n----\n\n" +
+		"## [v0.2.0] - 2020-01-02\n\nNo significant changes.\n\n\n----\n"
+
+	tests := []struct {
+		name, from, version, news string
+		tags                      []string
+		want, wantSource          string
+		wantError                 string
+	}{
+		{
+			name:       "auto prefers the news file",
+			from:       previousFromAuto,
+			version:    "v0.4.0",
+			news:       changelog,
+			tags:       []string{"v0.1.0"},
+			want:       "v0.3.0",
+			wantSource: "CHANGELOG.md",
+		},
+		{
+			name:       "news skips the new version",
+			from:       previousFromNews,
+			version:    "0.3.0",
+			news:       changelog,
+			want:       "v0.2.0",
+			wantSource: "CHANGELOG.md",
+		},
+		{
+			name:       "auto falls back to git tags",
+			from:       previousFromAuto,
+			version:    "v0.4.0",
+			tags:       []string{"v0.9.0", "v0.10.0", "v0.11.0-rc.1", "v0.4.0", "latest", "1.0"},
+			want:       "v0.11.0-rc.1",
+			wantSource: "git tags",
+		},
+		{
+			name:       "auto falls back to git tags without releases",
+			from:       previousFromAuto,
+			version:    "v0.4.0",
+			news:       "<!-- stentor output starts -->\n",
+			tags:       []string{"v0.3.0"},
+			want:       "v0.3.0",
+			wantSource: "git tags",
+		},
+		{
+			name:       "git ignores the news file",
+			from:       previousFromGit,
+			version:    "v0.4.0",
+			news:       changelog,
+			tags:       []string{"v0.1.0"},
+			want:       "v0.1.0",
+			wantSource: "git tags",
+		},
+		{
+			name:       "first release",
+			from:       previousFromAuto,
+			version:    "v0.1.0",
+			tags:       []string{"v0.1.0"},
+			wantSource: "git tags",
+		},
+		{
+			name:       "news without releases",
+			from:       previousFromNews,
+			version:    "v0.1.0",
+			news:       "<!-- stentor output starts -->\n",
+			tags:       []string{"v0.0.1"},
+			wantSource: "CHANGELOG.md",
+		},
+		{
+			name:      "news without a news file",
+			from:      previousFromNews,
+			version:   "v0.1.0",
+			wantError: "open CHANGELOG.md: no such file or directory",
+		},
+		{
+			name:      "invalid source",
+			from:      "tags",
+			version:   "v0.1.0",
+			wantError: `invalid previous source "tags": must be one of [auto news git]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := test.NewGitRepo(t, t.TempDir())
+			g.WriteFile("README.md", "readme")
+			g.Commit("initial commit")
+			g.WriteFile("main.go", "package main")
+			g.Commit("second commit")
+			for _, tag := range tt.tags {
+				g.Git("tag", tag)
+			}
+
+			wd, err := os.Getwd()
+			require.NoError(t, err)
+			require.NoError(t, os.Chdir(g.Dir))
+			defer os.Chdir(wd) // nolint:errcheck // defer func
+
+			if tt.news != "" {
+				require.NoError(t, os.WriteFile(filepath.Join(g.Dir, "CHANGELOG.md"), []byte(tt.news), 0600))
+			}
+
+			cfg, err := config.ParseBytes([]byte("[stentor]\nrepository = \"https://github.com/myname/myrepo\"\n"))
+			require.NoError(t, err)
+
+			got, source, err := detectPrevious(cfg, git.Repo{Dir: g.Dir, Env: g.Env}, tt.from, tt.version)
+			if tt.wantError != "" {
+				assert.EqualError(t, err, tt.wantError)
+				return
+			}
+
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, got)
+				assert.Equal(t, tt.wantSource, source)
+			}
+		})
+	}
+}
+
+func TestStentor_firstReleaseWithoutCommits(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(t *testing.T, dir string) []string
+	}{
+		{
+			name: "no repository",
+			setup: func(t *testing.T, dir string) []string {
+				return os.Environ()
+			},
+		},
+		{
+			name: "unborn HEAD",
+			setup: func(t *testing.T, dir string) []string {
+				return test.NewGitRepo(t, dir).Env
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			env := append(tt.setup(t, dir), "STENTOR_DATE=2020-01-02")
+			require.NoError(t, os.MkdirAll(filepath.Join(dir, ".stentor.d"), 0755))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, ".stentor.d", "stentor.toml"),
+				[]byte("[stentor]\nrepository = \"https://github.com/myname/myrepo\"\n"), 0600))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, ".stentor.d", "1.feature.md"), []byte("A feature."), 0600))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "CHANGELOG.md"),
+				[]byte("# Changelog\n\n<!-- stentor output starts -->\n"), 0600))
+
+			wd, err := os.Getwd()
+			require.NoError(t, err)
+			require.NoError(t, os.Chdir(dir))
+			defer os.Chdir(wd) // nolint:errcheck // defer func
+
+			run := func(args ...string) (int, string, string) {
+				stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+				s := New(dir, append([]string{appName}, args...), env, stderr, stdout)
+				return s.Run(), stdout.String(), stderr.String()
+			}
+
+			code, stdout, stderr := run("v0.1.0")
+			if assert.Equal(t, succesfulExitCode, code, stderr) {
+				assert.Equal(t, "## [v0.1.0] - 2020-01-02\n\n"+
+					"### Added\n\n"+
+					"- A feature.\n"+
+					"  [#1](https://github.com/myname/myrepo/issues/1)\n\n\n"+
+					"[v0.1.0]: https://github.com/myname/myrepo/releases/tag/v0.1.0\n\n\n"+
+					"----\n\n", stdout)
+			}
+			assert.Equal(t, "stentor: no PREVIOUS found in git tags, so this is the first release\n", stderr)
+
+			code, _, stderr = run("next-version")
+			assert.Equal(t, genericExitCode, code)
+			assert.Equal(t, "stentor: no PREVIOUS found in git tags, so this is the first release\n"+
+				"stentor: cannot bump PREVIOUS: there is no previous release, so give NEW for the first release\n", stderr)
+		})
+	}
+}
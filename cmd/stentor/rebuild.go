@@ -0,0 +1,165 @@
+// Copyright © 2020 The Stentor Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/wfscheper/stentor/archive"
+	"github.com/wfscheper/stentor/config"
+	"github.com/wfscheper/stentor/newsfile"
+	"github.com/wfscheper/stentor/release"
+)
+
+func (e Exec) runRebuild(args []string) int {
+	fs := e.newFlagSet(appName + " rebuild")
+
+	write := fs.Bool("write", getEnvBool(e.Env, "write", false), "update the news file instead of printing it")
+
+	e.setUsage(fs, "[OPTIONS]",
+		"Regenerate every release in the news file from the archived fragment files.\n\n"+
+			"The releases are rendered with the current templates,\n"+
+			"and replace everything below the start comment.")
+
+	if code, ok := parseCommandFlags(fs, args); !ok {
+		return code
+	}
+
+	if fs.NArg() > 0 {
+		e.err.Println("too many arguments")
+		return genericExitCode
+	}
+
+	cfg, err := e.loadConfig()
+	if err != nil {
+		e.err.Println(err)
+		return genericExitCode
+	}
+
+	if cfg.ArchiveDir == "" {
+		e.err.Println("cannot rebuild without an archive: archive_dir is not set")
+		return genericExitCode
+	}
+
+	releases, err := archive.Read(cfg.ArchiveDir)
+	if err != nil {
+		e.err.Printf("cannot read archive: %v", err)
+		return genericExitCode
+	}
+
+	if len(releases) == 0 {
+		e.err.Printf("no archived releases found in %s", cfg.ArchiveDir)
+		return genericExitCode
+	}
+
+	buf := &bytes.Buffer{}
+	for _, ar := range releases {
+		data, err := e.rebuildRelease(cfg, ar)
+		if err != nil {
+			e.err.Printf("cannot rebuild release %s: %v", ar.Version, err)
+			return genericExitCode
+		}
+		buf.Write(separateRelease(data))
+	}
+
+	// -release inserts each release right after the start comment,
+	// so what followed the comment before the first release ends the file.
+	// That is a newline in news files made by stentor init,
+	// which ends the line of a markdown start comment,
+	// or is the blank line after the others.
+	buf.WriteString("\n")
+
+	data, err := os.ReadFile(cfg.NewsFile)
+	if err != nil {
+		e.err.Println(err)
+		return genericExitCode
+	}
+
+	data, err = newsfile.ReplaceReleases(data, cfg.StartComment(), buf.Bytes())
+	if err != nil {
+		e.err.Printf("cannot update %s: %v", cfg.NewsFile, err)
+		return genericExitCode
+	}
+
+	if !*write {
+		e.out.Print(string(data))
+		return succesfulExitCode
+	}
+
+	if err := os.WriteFile(cfg.NewsFile, data, 0644); err != nil {
+		e.err.Printf("cannot update %s: %v", cfg.NewsFile, err)
+		return genericExitCode
+	}
+
+	e.out.Printf("rebuilt %d releases in %s", len(releases), cfg.NewsFile)
+	return succesfulExitCode
+}
+
+// rebuildRelease renders the archived release ar.
+//
+// The release uses the sections recorded in its manifest,
+// falling back to the configured ones,
+// and the fragments recorded in its manifest follow its fragment files.
+func (e Exec) rebuildRelease(cfg config.Config, ar archive.Release) ([]byte, error) {
+	// the first release has no previous_version
+	if ar.Version == "" {
+		return nil, errors.New("manifest must set version")
+	}
+
+	date, err := time.Parse("2006-01-02", ar.Date)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date: %w", err)
+	}
+
+	sections := ar.Sections
+	if len(sections) == 0 {
+		sections = cfg.Sections
+	}
+
+	fragments, err := e.fileSource(cfg, ar.Dir).Fragments(ar.PreviousVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range ar.Fragments {
+		fragments = append(fragments, f.Fragment())
+	}
+
+	if err := verifyFragmentSections(sections, fragments); err != nil {
+		return nil, err
+	}
+
+	r, err := release.New(cfg.Repository, cfg.Markup, ar.Version, ar.PreviousVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Date = date
+	r.SetSections(sections, fragments)
+	if err := setLinks(cfg, r); err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	if err := generateSections(buf, cfg, r); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
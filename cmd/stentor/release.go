@@ -0,0 +1,44 @@
+// Copyright © 2020 The Stentor Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"time"
+)
+
+func (e Exec) runReleaseCommand(args []string) int {
+	fs := e.newFlagSet(appName + " release")
+
+	date := e.releaseFlags(fs, "")
+	e.writeFlags(fs)
+
+	e.setUsage(fs, "[OPTIONS] NEW [PREVIOUS]",
+		"Update the news file with the changes from version PREVIOUS to NEW,\n"+
+			"and remove the fragment files.\n\n"+
+			"This is the same as '"+appName+" -release'.\n"+
+			"With -bump, NEW is omitted and computed from PREVIOUS.")
+
+	if code, ok := parseCommandFlags(fs, args); !ok {
+		return code
+	}
+
+	var err error
+	if e.date, err = time.Parse("2006-01-02", *date); err != nil {
+		e.err.Println(err)
+		return genericExitCode
+	}
+
+	return e.runRelease(fs, true)
+}
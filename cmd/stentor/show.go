@@ -0,0 +1,120 @@
+// Copyright © 2020 The Stentor Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+
+	"github.com/wfscheper/stentor/newsfile"
+)
+
+func (e Exec) runShow(args []string) int {
+	fs := e.newFlagSet(appName + " show")
+
+	latest := fs.Bool("latest", getEnvBool(e.Env, "latest", false), "show the most recent release")
+	stripHeading := fs.Bool(
+		"strip-heading",
+		getEnvBool(e.Env, "strip_heading", false),
+		"omit the release heading",
+	)
+
+	e.setUsage(fs, "[OPTIONS] VERSION", "Print the notes of release VERSION from the news file.")
+
+	if code, ok := parseCommandFlags(fs, args); !ok {
+		return code
+	}
+
+	switch {
+	case fs.NArg() > 1:
+		e.err.Println("too many arguments")
+		return genericExitCode
+	case fs.NArg() == 0 && !*latest:
+		e.err.Println("missing VERSION argument")
+		return genericExitCode
+	case fs.NArg() == 1 && *latest:
+		e.err.Println("cannot use VERSION with -latest")
+		return genericExitCode
+	}
+
+	cfg, err := e.loadConfig()
+	if err != nil {
+		e.err.Println(err)
+		return genericExitCode
+	}
+
+	data, err := os.ReadFile(cfg.NewsFile)
+	if err != nil {
+		e.err.Println(err)
+		return genericExitCode
+	}
+
+	entries, err := newsfile.ParseEntries(data, cfg.StartComment(), cfg.Markup)
+	if err != nil {
+		e.err.Printf("cannot read %s: %v", cfg.NewsFile, err)
+		return genericExitCode
+	}
+
+	var (
+		entry newsfile.Entry
+		found bool
+	)
+	if *latest {
+		if found = len(entries) > 0; found {
+			entry = entries[0]
+		}
+	} else {
+		entry, found = findEntry(entries, fs.Arg(0))
+	}
+
+	if !found {
+		if *latest {
+			e.err.Printf("no releases found in %s", cfg.NewsFile)
+		} else {
+			e.err.Printf("version %s not found in %s", fs.Arg(0), cfg.NewsFile)
+		}
+		return genericExitCode
+	}
+
+	if !*stripHeading {
+		e.out.Println(entry.Heading)
+		if entry.Body != "" {
+			e.out.Println()
+		}
+	}
+	if entry.Body != "" {
+		e.out.Print(entry.Body)
+	}
+
+	return succesfulExitCode
+}
+
+// findEntry returns the entry for version.
+//
+// Versions match with or without a leading "v".
+func findEntry(entries []newsfile.Entry, version string) (newsfile.Entry, bool) {
+	for _, e := range entries {
+		if e.Version == version {
+			return e, true
+		}
+	}
+
+	for _, e := range entries {
+		if sameVersion(e.Version, version) {
+			return e, true
+		}
+	}
+
+	return newsfile.Entry{}, false
+}
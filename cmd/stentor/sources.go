@@ -0,0 +1,84 @@
+// Copyright © 2020 The Stentor Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"github.com/wfscheper/stentor/config"
+	"github.com/wfscheper/stentor/fragment"
+	"github.com/wfscheper/stentor/internal/conventional"
+	"github.com/wfscheper/stentor/internal/git"
+)
+
+// fragmentSources returns the sources of fragments configured in cfg.
+func (e Exec) fragmentSources(cfg config.Config) []fragment.FragmentSource {
+	sources := make([]fragment.FragmentSource, 0, len(cfg.Sources))
+	for _, src := range cfg.Sources {
+		switch src.Type {
+		case fragment.SourceFiles:
+			sources = append(sources, e.fileSource(cfg, cfg.FragmentDir))
+		case fragment.SourceCommits:
+			sources = append(sources, conventional.Source{
+				Repo:    git.Repo{Dir: e.WorkDir, Env: e.Env},
+				Commits: *cfg.Commits,
+			})
+		case fragment.SourceCommand:
+			sources = append(sources, fragment.CommandSource{Command: src.Command, Dir: e.WorkDir, Env: e.Env})
+		}
+	}
+
+	return sources
+}
+
+// fileSource returns a source of the fragment files in dir,
+// which logs and skips invalid fragment files.
+func (e Exec) fileSource(cfg config.Config, dir string) fragment.FileSource {
+	return fragment.FileSource{
+		Dir: dir,
+		Ext: cfg.FragmentExtension(),
+		Invalid: func(fn string, err error) {
+			e.err.Printf("ignoring invalid fragment file %s: %v", fn, err)
+		},
+	}
+}
+
+// collectFragments returns the fragments of the release that follows previous,
+// read from all the sources configured in cfg,
+// and the fragment files they were read from.
+//
+// Fragments in sections that are not configured are an error.
+func (e Exec) collectFragments(cfg config.Config, previous string) ([]string, []fragment.Fragment, error) {
+	var fragments []fragment.Fragment
+	for _, src := range e.fragmentSources(cfg) {
+		f, err := src.Fragments(previous)
+		if err != nil {
+			return nil, nil, err
+		}
+		fragments = append(fragments, f...)
+	}
+
+	// verify the fragments against the configured sections
+	if err := verifyFragmentSections(cfg.Sections, fragments); err != nil {
+		return nil, nil, err
+	}
+
+	var files []string
+	for _, f := range fragments {
+		if f.Provenance.Source == fragment.SourceFiles {
+			files = append(files, f.Provenance.Ref)
+		}
+	}
+
+	return files, fragments, nil
+}
@@ -27,8 +27,11 @@ import (
 	"text/template"
 	"time"
 
+	"github.com/wfscheper/stentor"
+	"github.com/wfscheper/stentor/archive"
 	"github.com/wfscheper/stentor/config"
 	"github.com/wfscheper/stentor/fragment"
+	"github.com/wfscheper/stentor/hosting"
 	"github.com/wfscheper/stentor/internal/templates"
 	"github.com/wfscheper/stentor/newsfile"
 	"github.com/wfscheper/stentor/release"
@@ -56,10 +59,17 @@ type Exec struct {
 	out *log.Logger
 
 	// command-line options
-	configFile  *string
-	date        time.Time
-	release     *bool
-	showVersion *bool
+	configFile   *string
+	bump         *string
+	date         time.Time
+	diff         *bool
+	dryRun       *bool
+	format       *string
+	gitCommit    *bool
+	gitTag       *bool
+	previousFrom *string
+	release      *bool
+	showVersion  *bool
 }
 
 func New(wd string, args, env []string, err, out io.Writer) Exec {
@@ -72,7 +82,16 @@ func New(wd string, args, env []string, err, out io.Writer) Exec {
 	}
 }
 
-func (e Exec) Run() int { // nolint:gocognit // 31 > 30, but hard to see how to simplify
+func (e Exec) Run() int {
+	// dispatch to a subcommand
+	if len(e.Args) > 1 {
+		for _, cmd := range e.commands() {
+			if cmd.name == e.Args[1] {
+				return cmd.run(e.Args[2:])
+			}
+		}
+	}
+
 	// parse flags
 	e, fs, err := e.parseFlags()
 	if err != nil {
@@ -88,58 +107,64 @@ func (e Exec) Run() int { // nolint:gocognit // 31 > 30, but hard to see how to
 		return succesfulExitCode
 	}
 
-	if len(fs.Args()) > 2 {
-		e.err.Println("too many arguments")
+	return e.runRelease(fs, *e.release)
+}
+
+// runRelease renders the release described by the arguments of fs.
+//
+// If write is true, the release is added to the news file,
+// and the fragment files are archived, or removed if there is no archive directory.
+// Otherwise, the release is printed.
+func (e Exec) runRelease(fs *flag.FlagSet, write bool) int {
+	version, previousVersion, ok := e.releaseArgs(fs)
+	if !ok {
 		return genericExitCode
 	}
 
-	version := fs.Arg(0)
-	if version == "" {
-		e.err.Println("missing NEW argument")
+	// parse config file
+	cfg, err := e.loadConfig()
+	if err != nil {
+		e.err.Println(err)
 		return genericExitCode
 	}
 
-	previousVersion := fs.Arg(1)
-	if previousVersion == "" {
-		e.err.Println("missing PREVIOUS argument")
+	dryRun := *e.dryRun || *e.diff
+	if !e.checkReleaseFlags(write, dryRun) {
 		return genericExitCode
 	}
 
-	// determine path to config file
-	if !filepath.IsAbs(*e.configFile) {
-		*e.configFile = filepath.Join(e.WorkDir, *e.configFile)
+	if previousVersion == "" {
+		if previousVersion, err = e.detectPrevious(cfg, version); err != nil {
+			e.err.Println(err)
+			return genericExitCode
+		}
 	}
 
-	// parse config file
-	cfg, err := e.readConfig(*e.configFile)
+	fragmentFiles, fragments, err := e.collectFragments(cfg, previousVersion)
 	if err != nil {
 		e.err.Println(err)
 		return genericExitCode
 	}
 
-	fragmentFiles, err := cfg.FragmentFiles()
-	if err != nil {
-		e.err.Println(err)
-		return genericExitCode
+	if dryRun && len(fragments) == 0 {
+		e.err.Println("nothing to release: no fragments found")
+		return nothingToReleaseExitCode
 	}
 
-	// parse into fragments
-	var fragments []fragment.Fragment
-	for _, fn := range fragmentFiles {
-		f, err := fragment.Parse(fn)
-		if err != nil {
-			// log error and continue
-			e.err.Printf("ignoring invalid fragment file %s: %v", fn, err)
-			continue
+	if *e.bump != "" {
+		if version, err = nextVersion(cfg.Sections, fragments, previousVersion, *e.bump); err != nil {
+			e.err.Println(err)
+			return genericExitCode
 		}
-		fragments = append(fragments, *f)
+		e.err.Printf("using NEW %s", version)
 	}
 
-	// verify the fragments against the configured sections
-	err = verifyFragmentSections(cfg.Sections, fragments)
-	if err != nil {
-		e.err.Println(err)
-		return genericExitCode
+	if (write || dryRun) && cfg.ArchiveDir != "" {
+		// fail before the news file is touched, rather than leave the release half-applied
+		if err := archive.Check(cfg.ArchiveDir, version); err != nil {
+			e.err.Printf("cannot archive fragment files: %v", err)
+			return genericExitCode
+		}
 	}
 
 	r, err := release.New(cfg.Repository, cfg.Markup, version, previousVersion)
@@ -152,6 +177,14 @@ func (e Exec) Run() int { // nolint:gocognit // 31 > 30, but hard to see how to
 	r.Date = e.date
 
 	r.SetSections(cfg.Sections, fragments)
+	if err := setLinks(cfg, r); err != nil {
+		e.err.Println(err)
+		return genericExitCode
+	}
+
+	if !write && !dryRun && *e.format != "" {
+		return e.printReleaseData(r)
+	}
 
 	buf := &bytes.Buffer{}
 	if err := generateRelease(buf, cfg, r); err != nil {
@@ -159,50 +192,189 @@ func (e Exec) Run() int { // nolint:gocognit // 31 > 30, but hard to see how to
 		return genericExitCode
 	}
 
-	if !*e.release {
+	data := buf.Bytes()
+	if cfg.HeaderTemplate == "" {
+		// a header template ends with the start comment and its separator
+		data = separateRelease(data)
+	}
+	if dryRun {
+		return e.dryRunRelease(cfg, data, fragmentFiles, version)
+	}
+
+	if !write {
 		e.out.Print(buf.String())
 		return succesfulExitCode
 	}
 
-	if err := newsfile.WriteRelease(
-		cfg.NewsFile,
-		cfg.StartComment(),
-		append([]byte("\n"), buf.Bytes()...),
-		cfg.HeaderTemplate == "",
-	); err != nil {
+	return e.writeRelease(cfg, r, data, fragmentFiles, fragments)
+}
+
+// releaseArgs returns NEW and PREVIOUS from the arguments of fs.
+//
+// With -bump, NEW is computed later, so the only argument is PREVIOUS.
+func (e Exec) releaseArgs(fs *flag.FlagSet) (string, string, bool) {
+	maxArgs := 2
+	if *e.bump != "" {
+		maxArgs = 1
+	}
+
+	if fs.NArg() > maxArgs {
+		e.err.Println("too many arguments")
+		return "", "", false
+	}
+
+	if *e.bump != "" {
+		return "", fs.Arg(0), true
+	}
+
+	if fs.Arg(0) == "" {
+		e.err.Println("missing NEW argument")
+		return "", "", false
+	}
+
+	return fs.Arg(0), fs.Arg(1), true
+}
+
+// checkReleaseFlags reports whether the release flags can be used together.
+func (e Exec) checkReleaseFlags(write, dryRun bool) bool {
+	if *e.gitCommit || *e.gitTag {
+		switch {
+		case dryRun:
+			e.err.Println("-git-commit and -git-tag cannot be used with -dry-run or -diff")
+			return false
+		case !write:
+			e.err.Println("-git-commit and -git-tag require -release")
+			return false
+		}
+	}
+
+	switch *e.format {
+	case "", stentor.FormatJSON, stentor.FormatYAML:
+		return true
+	default:
+		e.err.Printf("invalid -format %q: must be %s or %s", *e.format, stentor.FormatJSON, stentor.FormatYAML)
+		return false
+	}
+}
+
+// printReleaseData prints r in the format set by -format.
+func (e Exec) printReleaseData(r *release.Release) int {
+	buf := &bytes.Buffer{}
+	if err := r.Encode(buf, *e.format); err != nil {
+		e.err.Println(err)
+		return genericExitCode
+	}
+
+	e.out.Print(buf.String())
+	return succesfulExitCode
+}
+
+// writeRelease adds data to the news file, writes the structured release if -format is set,
+// consumes the fragment files, and commits the release with -git-commit or -git-tag.
+func (e Exec) writeRelease(cfg config.Config, r *release.Release, data []byte,
+	fragmentFiles []string, fragments []fragment.Fragment) int {
+	var (
+		gr  *gitRelease
+		err error
+	)
+	if *e.gitCommit || *e.gitTag {
+		if gr, err = e.prepareGitRelease(cfg, r, fragmentFiles); err != nil {
+			e.err.Println(err)
+			return genericExitCode
+		}
+	}
+
+	// encode the structured release up front, so an error leaves the news file alone
+	var releaseData []byte
+	dataFile := e.releaseDataFile(cfg)
+	if dataFile != "" {
+		buf := &bytes.Buffer{}
+		if err := r.Encode(buf, *e.format); err != nil {
+			e.err.Printf("cannot write %s: %v", dataFile, err)
+			return genericExitCode
+		}
+		releaseData = buf.Bytes()
+	}
+
+	if err := newsfile.WriteRelease(cfg.NewsFile, cfg.StartComment(), data, cfg.HeaderTemplate == ""); err != nil {
 		e.err.Printf("cannot update %s: %v", cfg.NewsFile, err)
 		return genericExitCode
 	}
 
-	var failed bool
-	for _, f := range fragmentFiles {
-		if err := os.Remove(f); err != nil {
-			e.err.Printf("cannot remove fragment file %s: %v", f, err)
-			failed = true
+	if dataFile != "" {
+		if err := os.WriteFile(dataFile, releaseData, 0644); err != nil {
+			e.err.Printf("cannot write %s: %v", dataFile, err)
+			return genericExitCode
 		}
 	}
 
-	if failed {
+	if !e.consumeFragments(cfg, r, fragmentFiles, fragments) {
 		return genericExitCode
 	}
+
+	if gr != nil {
+		if err := gr.commit(*e.gitTag); err != nil {
+			e.err.Printf("cannot commit release: %v", err)
+			return genericExitCode
+		}
+	}
+
 	return succesfulExitCode
 }
 
+// releaseDataFile returns the name of the file -release writes the structured release to,
+// which is the news file with the extension of the -format flag,
+// or an empty string if -format is not set.
+func (e Exec) releaseDataFile(cfg config.Config) string {
+	if *e.format == "" {
+		return ""
+	}
+
+	return strings.TrimSuffix(cfg.NewsFile, filepath.Ext(cfg.NewsFile)) + "." + *e.format
+}
+
+// consumeFragments archives the fragment files of release r,
+// or removes them if there is no archive directory.
+// The other fragments of r are recorded in the manifest of the archive.
+//
+// Errors are logged, and consumeFragments returns false if there were any.
+func (e Exec) consumeFragments(cfg config.Config, r *release.Release, fragmentFiles []string, fragments []fragment.Fragment) bool {
+	if cfg.ArchiveDir != "" {
+		m := archive.Manifest{
+			Version:         r.Version,
+			PreviousVersion: r.PreviousVersion,
+			Date:            r.Date.Format("2006-01-02"),
+			Sections:        cfg.Sections,
+		}
+		for _, f := range fragments {
+			if f.Provenance.Source != fragment.SourceFiles {
+				m.Fragments = append(m.Fragments, archive.NewFragment(f))
+			}
+		}
+		if err := archive.Write(cfg.ArchiveDir, m, fragmentFiles); err != nil {
+			e.err.Printf("cannot archive fragment files: %v", err)
+			return false
+		}
+		return true
+	}
+
+	ok := true
+	for _, f := range fragmentFiles {
+		if err := os.Remove(f); err != nil {
+			e.err.Printf("cannot remove fragment file %s: %v", f, err)
+			ok = false
+		}
+	}
+
+	return ok
+}
+
 func (e Exec) parseFlags() (Exec, *flag.FlagSet, error) {
-	flags := flag.NewFlagSet(appName, flag.ContinueOnError)
-	flags.SetOutput(e.err.Writer())
+	flags := e.newFlagSet(appName)
 
-	e.configFile = flags.String(
-		"config",
-		getEnvString(e.Env, "config", filepath.Join(".stentor.d", "stentor.toml")),
-		"path to config file",
-	)
+	date := e.releaseFlags(flags, "")
 
-	date := flags.String(
-		"date",
-		getEnvString(e.Env, "date", time.Now().Format("2006-01-02")),
-		"date of release",
-	)
+	e.writeFlags(flags)
 
 	e.release = flags.Bool(
 		"release",
@@ -213,7 +385,7 @@ func (e Exec) parseFlags() (Exec, *flag.FlagSet, error) {
 	e.showVersion = flags.Bool("version", false, "show version information")
 
 	// setup usage information
-	e.setUsage(flags)
+	e.setUsage(flags, "[OPTIONS] NEW [PREVIOUS]", e.rootDescription())
 
 	// parse command line arguments
 	err := flags.Parse(e.Args[1:])
@@ -230,10 +402,84 @@ func (e Exec) parseFlags() (Exec, *flag.FlagSet, error) {
 	return e, flags, err
 }
 
+// releaseFlags defines the flags used to render a release on fs,
+// with defaultBump as the default of the -bump flag.
+//
+// It returns the value of the -date flag, which must be parsed once fs is.
+func (e *Exec) releaseFlags(fs *flag.FlagSet, defaultBump string) *string {
+	e.bump = fs.String(
+		"bump",
+		getEnvString(e.Env, "bump", defaultBump),
+		"compute NEW by bumping PREVIOUS: auto, major, minor, or patch",
+	)
+
+	date := fs.String(
+		"date",
+		getEnvString(e.Env, "date", time.Now().Format("2006-01-02")),
+		"date of release",
+	)
+
+	e.previousFrom = fs.String(
+		"previous-from",
+		getEnvString(e.Env, "previous_from", previousFromAuto),
+		"where to find PREVIOUS when it is omitted: auto, news, or git",
+	)
+
+	return date
+}
+
+// writeFlags defines the flags that control how a release is written on fs.
+func (e *Exec) writeFlags(fs *flag.FlagSet) {
+	e.diff = fs.Bool(
+		"diff",
+		getEnvBool(e.Env, "diff", false),
+		"like -dry-run, but print a diff of the news file",
+	)
+
+	e.dryRun = fs.Bool(
+		"dry-run",
+		getEnvBool(e.Env, "dry_run", false),
+		"print what -release would change, without changing anything",
+	)
+
+	e.format = fs.String(
+		"format",
+		getEnvString(e.Env, "format", ""),
+		"print the release as json or yaml; with -release, also write it next to the news file",
+	)
+
+	e.gitCommit = fs.Bool(
+		"git-commit",
+		getEnvBool(e.Env, "git_commit", false),
+		"commit the changes to the news file and fragments",
+	)
+
+	e.gitTag = fs.Bool(
+		"git-tag",
+		getEnvBool(e.Env, "git_tag", false),
+		"like -git-commit, and tag the commit with NEW",
+	)
+}
+
 func (e Exec) displayVersion() {
 	e.out.Printf("%s %s built from %s on %s\n", appName, version, commit, buildDate)
 }
 
+// loadConfig reads the config file named by the -config flag.
+//
+// Relative paths are resolved against the working directory.
+func (e Exec) loadConfig() (config.Config, error) {
+	return e.readConfig(e.workPath(*e.configFile))
+}
+
+// workPath returns fn resolved against the working directory.
+func (e Exec) workPath(fn string) string {
+	if filepath.IsAbs(fn) {
+		return fn
+	}
+	return filepath.Join(e.WorkDir, fn)
+}
+
 func (Exec) readConfig(fn string) (config.Config, error) {
 	data, err := os.ReadFile(fn)
 	if err != nil {
@@ -252,7 +498,33 @@ func (Exec) readConfig(fn string) (config.Config, error) {
 	return cfg, nil
 }
 
-func (e Exec) setUsage(fs *flag.FlagSet) {
+// newFlagSet returns a FlagSet named name that reports errors to stderr.
+//
+// All stentor commands share the -config flag, so it is defined here.
+func (e *Exec) newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(e.err.Writer())
+
+	e.configFile = flags.String(
+		"config",
+		getEnvString(e.Env, "config", filepath.Join(".stentor.d", "stentor.toml")),
+		"path to config file",
+	)
+
+	return flags
+}
+
+// rootDescription returns the help text for stentor when run without a command.
+func (e Exec) rootDescription() string {
+	return "Update a news file with the changes from version PREVIOUS to NEW.\n\n" +
+		"If PREVIOUS is omitted, it is the most recent release in the news file,\n" +
+		"or the highest version tag reachable from HEAD.\n" +
+		"With -bump, NEW is omitted and computed from PREVIOUS.\n\n" +
+		"Commands:\n\n" + commandList(e.commands()) +
+		"\nRun '" + appName + " COMMAND -help' for more information on a command."
+}
+
+func (e Exec) setUsage(fs *flag.FlagSet, synopsis, description string) {
 	var flagsUsage bytes.Buffer
 	tw := tabwriter.NewWriter(&flagsUsage, 0, 4, 2, ' ', 0)
 	fs.VisitAll(func(f *flag.Flag) {
@@ -268,26 +540,19 @@ func (e Exec) setUsage(fs *flag.FlagSet) {
 
 	tw.Flush()
 	fs.Usage = func() {
-		e.out.Printf(`Usage: %[1]s [OPTIONS] NEW PREVIOUS
+		e.out.Printf(`Usage: %s %s
 
-Update a news file with the changes from version PREVIOUS to NEW.
+%s
 
 Flags:
 
-%s`, appName, flagsUsage.String())
+%s`, fs.Name(), synopsis, description, flagsUsage.String())
 	}
 }
 
 func generateRelease(w io.Writer, cfg config.Config, r *release.Release) error {
-	var loadTemplate = func(name, fallback string) (*template.Template, error) {
-		if name != "" {
-			return templates.Parse(filepath.Join(cfg.FragmentDir, name))
-		}
-		return templates.New(fallback)
-	}
-
 	if cfg.HeaderTemplate != "" {
-		headerTemplate, err := loadTemplate(cfg.HeaderTemplate, cfg.Markup+"-header")
+		headerTemplate, err := loadTemplate(cfg, cfg.HeaderTemplate, cfg.Markup+"-header")
 		if err != nil {
 			return fmt.Errorf("cannot parse header template: %w", err)
 		}
@@ -297,7 +562,30 @@ func generateRelease(w io.Writer, cfg config.Config, r *release.Release) error {
 		}
 	}
 
-	sectionTemplate, err := loadTemplate(cfg.SectionTemplate, cfg.Hosting+"-"+cfg.Markup+"-section")
+	return generateSections(w, cfg, r)
+}
+
+// separateRelease returns data, a rendered release,
+// prefixed with what separates it from the start comment or the release above it.
+//
+// Both -release and rebuild use it, so a rebuilt news file matches the released one.
+func separateRelease(data []byte) []byte {
+	return append([]byte("\n"), data...)
+}
+
+// setLinks resolves the links of r with the hosting provider and trackers configured in cfg.
+func setLinks(cfg config.Config, r *release.Release) error {
+	p, err := hosting.New(cfg.Hosting, r.Repository, cfg.URLs)
+	if err != nil {
+		return err
+	}
+
+	return r.SetLinks(p, cfg.Trackers)
+}
+
+// generateSections renders the sections of r, without the news file header.
+func generateSections(w io.Writer, cfg config.Config, r *release.Release) error {
+	sectionTemplate, err := loadTemplate(cfg, cfg.SectionTemplate, templates.SectionName(cfg.Hosting, cfg.Markup))
 	if err != nil {
 		return fmt.Errorf("cannot parse section template: %w", err)
 	}
@@ -309,6 +597,15 @@ func generateRelease(w io.Writer, cfg config.Config, r *release.Release) error {
 	return nil
 }
 
+// loadTemplate parses the template name in the fragment directory,
+// or the built-in template fallback if name is empty or config.BuiltinTemplate.
+func loadTemplate(cfg config.Config, name, fallback string) (*template.Template, error) {
+	if !isBuiltinTemplate(name) {
+		return templates.Parse(filepath.Join(cfg.FragmentDir, name))
+	}
+	return templates.New(fallback)
+}
+
 func getEnvBool(env []string, key string, def bool) bool {
 	key = strings.ToUpper(appName) + "_" + strings.ToUpper(key)
 	if v, ok := lookupEnv(env, key); ok {
@@ -0,0 +1,245 @@
+// Copyright © 2020 The Stentor Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/wfscheper/stentor/config"
+	"github.com/wfscheper/stentor/internal/diff"
+	"github.com/wfscheper/stentor/internal/templates"
+)
+
+// templateKinds are the kinds of templates stentor renders,
+// in the order they are processed.
+var templateKinds = []string{"section", "header"}
+
+func (e Exec) templatesCommands() []command {
+	return []command{
+		{"diff", "show how the configured templates differ from the built-in templates", e.runTemplatesDiff},
+		{"eject", "copy built-in templates into the fragment directory", e.runTemplatesEject},
+	}
+}
+
+func (e Exec) runTemplates(args []string) int {
+	fs := e.newFlagSet(appName + " templates")
+	e.setUsage(fs, "COMMAND [OPTIONS]", "Manage the templates used to render releases.\n\n"+
+		"Commands:\n\n"+strings.TrimSuffix(commandList(e.templatesCommands()), "\n"))
+
+	if len(args) > 0 {
+		for _, cmd := range e.templatesCommands() {
+			if cmd.name == args[0] {
+				return cmd.run(args[1:])
+			}
+		}
+	}
+
+	if code, ok := parseCommandFlags(fs, args); !ok {
+		return code
+	}
+
+	if fs.NArg() == 0 {
+		e.err.Println("missing COMMAND argument")
+	} else {
+		e.err.Printf("unknown command %q", fs.Arg(0))
+	}
+	fs.Usage()
+	return genericExitCode
+}
+
+func (e Exec) runTemplatesEject(args []string) int {
+	fs := e.newFlagSet(appName + " templates eject")
+	force := fs.Bool("force", getEnvBool(e.Env, "force", false), "overwrite existing templates and settings")
+
+	e.setUsage(fs, "[OPTIONS] [KIND...]", "Copy the built-in templates for the configured hosting and markup\n"+
+		"into the fragment directory, and point the config file at the copies.\n\n"+
+		"KIND is one of 'section' or 'header', and defaults to 'section'.")
+
+	if code, ok := parseCommandFlags(fs, args); !ok {
+		return code
+	}
+
+	kinds, err := parseTemplateKinds(fs.Args())
+	if err != nil {
+		e.err.Println(err)
+		return genericExitCode
+	}
+
+	cfg, err := e.loadConfig()
+	if err != nil {
+		e.err.Println(err)
+		return genericExitCode
+	}
+
+	configFile := e.workPath(*e.configFile)
+
+	configData, err := os.ReadFile(configFile)
+	if err != nil {
+		e.err.Println(err)
+		return genericExitCode
+	}
+
+	for _, kind := range kinds {
+		name := builtinTemplateName(cfg, kind)
+		data, err := templates.Read(name)
+		if err != nil {
+			e.err.Printf("no built-in %s template for %s", kind, describeTemplate(cfg, kind))
+			return genericExitCode
+		}
+
+		if current := configuredTemplate(cfg, kind); !isBuiltinTemplate(current) && current != name && !*force {
+			e.err.Printf("%s_template is already set to %q, use -force to replace it", kind, current)
+			return genericExitCode
+		}
+
+		fn := filepath.Join(cfg.FragmentDir, name)
+		if _, err := os.Stat(fn); err == nil && !*force {
+			e.err.Printf("%s already exists, use -force to overwrite it", fn)
+			return genericExitCode
+		}
+
+		if err := os.WriteFile(fn, data, 0644); err != nil {
+			e.err.Println(err)
+			return genericExitCode
+		}
+
+		if configData, err = config.SetValue(configData, kind+"_template", name); err != nil {
+			e.err.Printf("cannot update config file: %v", err)
+			return genericExitCode
+		}
+
+		e.out.Printf("ejected %s template to %s", kind, fn)
+	}
+
+	if err := os.WriteFile(configFile, configData, 0644); err != nil {
+		e.err.Printf("cannot update config file: %v", err)
+		return genericExitCode
+	}
+
+	return succesfulExitCode
+}
+
+func (e Exec) runTemplatesDiff(args []string) int {
+	fs := e.newFlagSet(appName + " templates diff")
+	e.setUsage(fs, "[OPTIONS] [KIND...]", "Show how the configured templates differ from the built-in templates\n"+
+		"for the configured hosting and markup.\n\n"+
+		"KIND is one of 'section' or 'header', and defaults to all configured templates.\n"+
+		"Exits with a non-zero status if any template differs.")
+
+	if code, ok := parseCommandFlags(fs, args); !ok {
+		return code
+	}
+
+	kinds := templateKinds
+	if fs.NArg() > 0 {
+		var err error
+		if kinds, err = parseTemplateKinds(fs.Args()); err != nil {
+			e.err.Println(err)
+			return genericExitCode
+		}
+	}
+
+	cfg, err := e.loadConfig()
+	if err != nil {
+		e.err.Println(err)
+		return genericExitCode
+	}
+
+	exitCode := succesfulExitCode
+	for _, kind := range kinds {
+		current := configuredTemplate(cfg, kind)
+		if current == config.BuiltinTemplate {
+			// the built-in template cannot differ from itself
+			continue
+		}
+		if current == "" {
+			if fs.NArg() > 0 {
+				e.err.Printf("%s_template is not set", kind)
+				exitCode = genericExitCode
+			}
+			continue
+		}
+
+		builtin, err := templates.Read(builtinTemplateName(cfg, kind))
+		if err != nil {
+			e.err.Printf("no built-in %s template for %s", kind, describeTemplate(cfg, kind))
+			exitCode = genericExitCode
+			continue
+		}
+
+		fn := filepath.Join(cfg.FragmentDir, current)
+		data, err := os.ReadFile(fn)
+		if err != nil {
+			e.err.Println(err)
+			exitCode = genericExitCode
+			continue
+		}
+
+		if d := diff.Unified("builtin/"+builtinTemplateName(cfg, kind), fn, builtin, data); d != "" {
+			e.out.Print(d)
+			exitCode = genericExitCode
+		}
+	}
+
+	return exitCode
+}
+
+// builtinTemplateName returns the name of the built-in template of kind for cfg.
+func builtinTemplateName(cfg config.Config, kind string) string {
+	if kind == "header" {
+		return cfg.Markup + "-header"
+	}
+	return templates.SectionName(cfg.Hosting, cfg.Markup)
+}
+
+// configuredTemplate returns the name of the template of kind set in cfg.
+func configuredTemplate(cfg config.Config, kind string) string {
+	if kind == "header" {
+		return cfg.HeaderTemplate
+	}
+	return cfg.SectionTemplate
+}
+
+// isBuiltinTemplate returns true if the template setting name selects a built-in template.
+func isBuiltinTemplate(name string) bool {
+	return name == "" || name == config.BuiltinTemplate
+}
+
+// describeTemplate describes the settings that select the built-in template of kind.
+func describeTemplate(cfg config.Config, kind string) string {
+	if kind == "header" {
+		return fmt.Sprintf("markup %q", cfg.Markup)
+	}
+	return fmt.Sprintf("hosting %q and markup %q", cfg.Hosting, cfg.Markup)
+}
+
+// parseTemplateKinds validates the template kinds in args, defaulting to section templates.
+func parseTemplateKinds(args []string) ([]string, error) {
+	if len(args) == 0 {
+		return []string{"section"}, nil
+	}
+
+	for _, arg := range args {
+		if arg != "section" && arg != "header" {
+			return nil, errors.New("template kind must be one of 'section' or 'header'")
+		}
+	}
+
+	return args, nil
+}
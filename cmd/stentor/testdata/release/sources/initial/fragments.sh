@@ -0,0 +1 @@
+echo '[{"section": "fix", "issue": "12", "text": "A fix since '"$STENTOR_PREVIOUS"'."}]'
@@ -21,50 +21,72 @@ import (
 	"fmt"
 	"net/url"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/pelletier/go-toml"
 	"github.com/wfscheper/stentor"
+	"github.com/wfscheper/stentor/fragment"
 )
 
 const (
 	DefaultConfigDir = ".stentor.d"
+	// TrackerID is replaced by the issue ID in the URL of a tracker.
+	TrackerID = "{id}"
+	// BuiltinTemplate is the template name that selects the built-in template,
+	// eg. to have stentor render the header of the news file without ejecting it.
+	BuiltinTemplate = "builtin"
 )
 
 var (
+	// ErrBadBump is the error returned if a config file section has an unsupported bump level.
+	ErrBadBump = errors.New("bump must be one of 'major', 'minor', or 'patch'")
 	// ErrBadHosting is the error returned if a config file references an unsupported hosting provider.
-	ErrBadHosting = errors.New("hosting must be one of 'github' or 'gitlab'")
+	ErrBadHosting = errors.New(
+		"hosting must be one of 'github', 'gitlab', 'bitbucket', 'gitea', 'forgejo', 'azure', or 'custom'")
 	// ErrBadMarkup is the error returned if a config file references an unsupported style of markup.
-	ErrBadMarkup = errors.New("markup must be one of 'markdown' or 'rst'")
+	ErrBadMarkup = errors.New("markup must be one of 'asciidoc', 'markdown', or 'rst'")
 	// ErrBadSections is the error returned if a config file contains an empty sections list.
 	ErrBadSections = errors.New("must define at least one section")
+	// ErrBadSourceType is the error returned if a config file source has an unsupported type.
+	ErrBadSourceType = errors.New("type must be one of 'files', 'commits', or 'command'")
 	// ErrMissingRepository is the error returned if a config file does not declare a repository.
 	ErrMissingRepository = errors.New("repository is required")
 
+	stentorTableRE = regexp.MustCompile(`^\s*\[\s*stentor\s*\]\s*(#.*)?$`)
+	tableRE        = regexp.MustCompile(`^\s*\[`)
+	keyRE          = regexp.MustCompile(`^(\s*)([A-Za-z0-9_-]+)\s*=`)
+
 	defaultSectionConfig = []Section{
 		{
 			Name:      "Security",
 			ShortName: "security",
+			Bump:      stentor.BumpPatch,
 		},
 		{
 			Name:      "Deprecated",
 			ShortName: "deprecate",
+			Bump:      stentor.BumpMinor,
 		},
 		{
 			Name:      "Removed",
 			ShortName: "remove",
+			Bump:      stentor.BumpMajor,
 		},
 		{
 			Name:      "Changed",
 			ShortName: "change",
+			Bump:      stentor.BumpMajor,
 		},
 		{
 			Name:      "Added",
 			ShortName: "feature",
+			Bump:      stentor.BumpMinor,
 		},
 		{
 			Name:      "Fixed",
 			ShortName: "fix",
+			Bump:      stentor.BumpPatch,
 		},
 	}
 )
@@ -77,22 +99,44 @@ type tomlConfig struct {
 type Config struct {
 	// Repository is the name of your repository in <username>/<repo name> format.
 	Repository string `toml:"repository,omitempty"`
+	// Commits configures fragments generated from Conventional Commits.
+	// If nil, fragments are only read from files.
+	Commits *Commits `toml:"commits,omitempty"`
+	// CommitMessage is the template of the commit message used by the -git-commit flag.
+	// Defaults to "Release {{ .Version }}".
+	CommitMessage string `toml:"commit_message,omitempty"`
 	// FragmentDir is the path to the directory holding the project's news fragments.
 	// Defaults to '.stentor.d'.
 	FragmentDir string `toml:"fragment_dir,omitempty" yaml:"fragment_dir,omitempty"`
+	// ArchiveDir is the path to the directory where the fragment files of a release are archived.
+	// If empty, the fragment files are deleted instead.
+	ArchiveDir string `toml:"archive_dir,omitempty"`
 	// Hosting is the source repository host.
 	// When Markup is set to markdown, this also determines the markdown flavor.
-	// Currently, github and gitlab are supported.
+	// Currently, github, gitlab, bitbucket (Server and Data Center), gitea, forgejo,
+	// azure (Azure Repos), and custom are supported.
 	// Defaults to github.
 	Hosting string `toml:"hosting,omitempty"`
+	// URLs are the URL patterns of the custom hosting provider.
+	// They are required when Hosting is custom, and invalid otherwise.
+	URLs *URLs `toml:"urls,omitempty"`
 	// Markup sets the format of your changelog.
-	// Currently, markdown and rst (ReStructuredText) are supported.
+	// Currently, asciidoc, markdown, and rst (ReStructuredText) are supported.
 	// Defaults to markdown
 	Markup string `toml:"markup,omitempty"`
+	// Sources are the sources the fragments of a release are read from.
+	// Defaults to the fragment files, and the Conventional Commits if Commits is set.
+	Sources []Source `toml:"sources,omitempty"`
+	// Trackers are external issue trackers, eg. Jira, that issue IDs link to.
+	// The first tracker whose pattern matches an issue ID is used.
+	// Issues that match no tracker link to the hosting provider.
+	Trackers []Tracker `toml:"trackers,omitempty"`
 	// Sections define the different news sections.
 	// Sections will be listed in the order in which they are defined here.
 	Sections []Section `toml:"sections,omitempty"`
 	// HeaderTemplate is the name of the template used to render the header of the news file.
+	// If set, the header is rendered again on every release.
+	// BuiltinTemplate selects the built-in header template for the markup.
 	HeaderTemplate string `toml:"header_template,omitempty"`
 	// SectionTemplate is the name of the template used to render the individual sections of the news file.
 	SectionTemplate string `toml:"section_template,omitempty"`
@@ -109,6 +153,76 @@ func ParseBytes(data []byte) (Config, error) {
 	return c, nil
 }
 
+// Marshal returns the toml encoding of c.
+func Marshal(c Config) ([]byte, error) {
+	data, err := toml.Marshal(tomlConfig{&c})
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimLeft(data, "\n"), nil
+}
+
+// SetValue sets the string value of key in the stentor table of the toml config file data.
+//
+// Unlike Marshal, SetValue only edits the line defining key,
+// or adds a new line if key is not set,
+// so the formatting and comments of the rest of the file are preserved.
+func SetValue(data []byte, key, value string) ([]byte, error) {
+	line := key + " = " + quote(value)
+	lines := strings.Split(string(data), "\n")
+
+	inTable, tableLine, indent := false, -1, ""
+	for i, l := range lines {
+		switch {
+		case stentorTableRE.MatchString(l):
+			inTable, tableLine = true, i
+			continue
+		case tableRE.MatchString(l):
+			inTable = false
+			continue
+		case !inTable:
+			continue
+		}
+
+		if m := keyRE.FindStringSubmatch(l); m != nil {
+			if m[2] == key {
+				lines[i] = m[1] + line
+				return checkConfig(lines)
+			}
+			if indent == "" {
+				indent = m[1]
+			}
+		}
+	}
+
+	if tableLine < 0 {
+		if len(data) > 0 && !bytes.HasSuffix(data, []byte("\n")) {
+			lines = append(lines, "")
+		}
+		lines = append(lines[:len(lines)-1], "[stentor]", line, "")
+		return checkConfig(lines)
+	}
+
+	lines = append(lines[:tableLine+1], append([]string{indent + line}, lines[tableLine+1:]...)...)
+	return checkConfig(lines)
+}
+
+// checkConfig joins lines into a config file, and verifies that it parses.
+func checkConfig(lines []string) ([]byte, error) {
+	data := []byte(strings.Join(lines, "\n"))
+	if _, err := parseConfig(data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// quote returns s as a toml basic string.
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`).Replace(s) + `"`
+}
+
 func parseConfig(data []byte) (Config, error) {
 	var c Config
 	if err := toml.NewDecoder(bytes.NewReader(data)).Strict(true).Decode(&tomlConfig{&c}); err != nil {
@@ -130,6 +244,8 @@ func parseConfig(data []byte) (Config, error) {
 	if c.NewsFile == "" {
 		c.NewsFile = "CHANGELOG"
 		switch c.Markup {
+		case stentor.MarkupAsciiDoc:
+			c.NewsFile += ".adoc"
 		case stentor.MarkupMD:
 			c.NewsFile += ".md"
 		case stentor.MarkupRST:
@@ -143,6 +259,32 @@ func parseConfig(data []byte) (Config, error) {
 		c.Sections = defaultSectionConfig
 	}
 
+	if len(c.Sources) == 0 {
+		c.Sources = []Source{{Type: fragment.SourceFiles}}
+		if c.Commits != nil {
+			c.Sources = append(c.Sources, Source{Type: fragment.SourceCommits})
+		}
+	}
+
+	// a commits source uses the default commits config if none is set
+	if c.Commits == nil {
+		for _, src := range c.Sources {
+			if src.Type == fragment.SourceCommits {
+				c.Commits = &Commits{}
+				break
+			}
+		}
+	}
+
+	if c.Commits != nil {
+		if len(c.Commits.Types) == 0 {
+			c.Commits.Types = map[string]string{"feat": "feature", "fix": "fix"}
+		}
+		if c.Commits.BreakingSection == "" {
+			c.Commits.BreakingSection = "change"
+		}
+	}
+
 	return c, nil
 }
 
@@ -159,40 +301,160 @@ func ValidateConfig(c Config) error {
 	case !strings.HasPrefix(u.Scheme, "http"):
 		return fmt.Errorf("invalid repository: must be a http or https URL")
 	}
-	// hosting must be github or gitlab
-	if c.Hosting != stentor.HostingGithub && c.Hosting != stentor.HostingGitlab {
+	// hosting must be a built-in hosting provider, or custom
+	switch c.Hosting {
+	case stentor.HostingGithub, stentor.HostingGitlab, stentor.HostingBitbucket,
+		stentor.HostingGitea, stentor.HostingForgejo, stentor.HostingAzure:
+		if c.URLs != nil {
+			return fmt.Errorf("invalid urls: only valid with hosting %q", stentor.HostingCustom)
+		}
+	case stentor.HostingCustom:
+		if err := validateURLs(c.URLs); err != nil {
+			return fmt.Errorf("invalid urls: %w", err)
+		}
+	default:
 		return ErrBadHosting
 	}
-	// markup must be markdown or rst
-	if c.Markup != stentor.MarkupMD && c.Markup != stentor.MarkupRST {
+	// markup must be asciidoc, markdown, or rst
+	switch c.Markup {
+	case stentor.MarkupAsciiDoc, stentor.MarkupMD, stentor.MarkupRST:
+	default:
 		return ErrBadMarkup
 	}
 	// must have at least one section
 	if len(c.Sections) < 1 {
 		return ErrBadSections
 	}
+	// commits must map to known sections
+	if c.Commits != nil {
+		if err := validateCommits(*c.Commits, c.Sections); err != nil {
+			return fmt.Errorf("invalid commits: %w", err)
+		}
+	}
+	// trackers must have a valid pattern and url
+	for i, t := range c.Trackers {
+		if err := validateTracker(t); err != nil {
+			return fmt.Errorf("invalid tracker %d: %w", i+1, err)
+		}
+	}
+	// sources must have a known type
+	for i, src := range c.Sources {
+		if err := validateSource(src); err != nil {
+			return fmt.Errorf("invalid source %d: %w", i+1, err)
+		}
+	}
+	// bump must be empty, major, minor, or patch
+	for _, s := range c.Sections {
+		switch s.Bump {
+		case "", stentor.BumpMajor, stentor.BumpMinor, stentor.BumpPatch:
+		default:
+			return fmt.Errorf("invalid section %s: %w", s.ShortName, ErrBadBump)
+		}
+	}
 	return nil
 }
 
-// FragmentFiles returns the names of all the fragment files.
-func (c Config) FragmentFiles() ([]string, error) {
-	var glob string
+func validateCommits(c Commits, sections []Section) error {
+	known := map[string]bool{}
+	for _, s := range sections {
+		known[s.ShortName] = true
+	}
+
+	for commitType, section := range c.Types {
+		if !known[section] {
+			return fmt.Errorf("type %s maps to unknown section %q", commitType, section)
+		}
+	}
+
+	if !known[c.BreakingSection] {
+		return fmt.Errorf("unknown breaking_section %q", c.BreakingSection)
+	}
+
+	return nil
+}
+
+func validateURLs(u *URLs) error {
+	if u == nil {
+		return fmt.Errorf("required with hosting %q", stentor.HostingCustom)
+	}
+
+	for _, p := range []struct{ key, pattern string }{
+		{"compare", u.Compare},
+		{"issue", u.Issue},
+		{"pull_request", u.PullRequest},
+		{"commit", u.Commit},
+		{"tag", u.Tag},
+	} {
+		if p.pattern == "" {
+			return fmt.Errorf("%s is required", p.key)
+		}
+	}
+
+	return nil
+}
+
+func validateTracker(t Tracker) error {
+	if t.Pattern == "" {
+		return errors.New("pattern is required")
+	}
+	if _, err := regexp.Compile(t.Pattern); err != nil {
+		return fmt.Errorf("invalid pattern: %w", err)
+	}
+	if !strings.Contains(t.URL, TrackerID) {
+		return fmt.Errorf("url must contain %s", TrackerID)
+	}
+
+	return nil
+}
+
+func validateSource(s Source) error {
+	switch s.Type {
+	case fragment.SourceFiles, fragment.SourceCommits:
+		if len(s.Command) > 0 {
+			return errors.New("command is only valid for command sources")
+		}
+	case fragment.SourceCommand:
+		if len(s.Command) == 0 {
+			return errors.New("command is required")
+		}
+	default:
+		return ErrBadSourceType
+	}
+
+	return nil
+}
+
+// FragmentExtension returns the file extension of fragment files,
+// or an empty string if the markup is not recognized.
+func (c Config) FragmentExtension() string {
 	switch c.Markup {
+	case stentor.MarkupAsciiDoc:
+		return ".adoc"
 	case stentor.MarkupMD:
-		glob = "*.md"
+		return ".md"
 	case stentor.MarkupRST:
-		glob = "*.rst"
+		return ".rst"
 	default:
+		return ""
+	}
+}
+
+// FragmentFiles returns the names of all the fragment files.
+func (c Config) FragmentFiles() ([]string, error) {
+	ext := c.FragmentExtension()
+	if ext == "" {
 		return nil, fmt.Errorf("unknown markup %s", c.Markup)
 	}
 
-	return filepath.Glob(filepath.Join(c.FragmentDir, glob))
+	return filepath.Glob(filepath.Join(c.FragmentDir, "*"+ext))
 }
 
 // StartComment returns the markup-specific comment string stentor uses to
 // separate the news file header from the releases.
 func (c Config) StartComment() string {
 	switch c.Markup {
+	case stentor.MarkupAsciiDoc:
+		return stentor.CommentAsciiDoc
 	case stentor.MarkupMD:
 		return stentor.CommentMD
 	case stentor.MarkupRST:
@@ -211,4 +473,67 @@ type Section struct {
 	// ShowAlways is a boolean indicating whether to show the section even if there are no news items.
 	// This is a pointer so that we can use omitempty, and still render false values.
 	ShowAlways *bool `toml:"show_always,omitempty" yaml:"show_always,omitempty"`
+	// Bump is the level of the version bump caused by fragments in this section:
+	// major, minor, or patch.
+	// Defaults to patch.
+	Bump string `toml:"bump,omitempty"`
+	// Skeleton is the initial text of fragment files created for this section by the new command.
+	Skeleton string `toml:"skeleton,omitempty"`
+}
+
+// Commits configures how Conventional Commits are turned into fragments.
+//
+// See https://www.conventionalcommits.org/en/v1.0.0/.
+type Commits struct {
+	// Types maps commit types, eg. feat, to the short names of sections.
+	// Commits with other types are ignored.
+	// Defaults to feat for feature, and fix for fix.
+	Types map[string]string `toml:"types,omitempty"`
+	// BreakingSection is the short name of the section for breaking changes,
+	// whatever their commit type.
+	// Defaults to change.
+	BreakingSection string `toml:"breaking_section,omitempty"`
+}
+
+// Source configures a source of fragments.
+type Source struct {
+	// Type is the type of the source:
+	// files reads the fragment files in FragmentDir,
+	// commits reads the Conventional Commits made since the previous release,
+	// and command reads the fragments written by Command.
+	Type string `toml:"type"`
+	// Command is the command run by a command source, and its arguments.
+	Command []string `toml:"command,omitempty"`
+}
+
+// Tracker is an external issue tracker.
+type Tracker struct {
+	// Pattern is a regular expression matching the IDs of the tracker's issues,
+	// eg. ^[A-Z]+-\d+$ for Jira.
+	Pattern string `toml:"pattern"`
+	// URL is the URL of an issue, where {id} is replaced by the issue ID,
+	// eg. https://jira.example.com/browse/{id}.
+	URL string `toml:"url"`
+}
+
+// URLs are the URL patterns of a custom hosting provider.
+//
+// In each pattern, {repository} is replaced by the repository URL.
+// The compare pattern replaces {previous} and {version} with the versions being compared,
+// and the other patterns replace {id} with the ID of the issue, pull request, commit, or tag.
+type URLs struct {
+	// Compare is the URL of the changes between two versions,
+	// eg. {repository}/compare/{previous}...{version}.
+	Compare string `toml:"compare"`
+	// Issue is the URL of an issue, eg. {repository}/issues/{id}.
+	Issue string `toml:"issue"`
+	// PullRequest is the URL of a pull request, eg. {repository}/pulls/{id}.
+	PullRequest string `toml:"pull_request"`
+	// PullRequestPrefix starts the label of a pull request link, eg. ! for !12.
+	// Defaults to #.
+	PullRequestPrefix string `toml:"pull_request_prefix,omitempty"`
+	// Commit is the URL of a commit, eg. {repository}/commit/{id}.
+	Commit string `toml:"commit"`
+	// Tag is the URL of a tag, eg. {repository}/releases/tag/{id}.
+	Tag string `toml:"tag"`
 }
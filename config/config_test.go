@@ -31,6 +31,8 @@ func TestConfig_marshal(t *testing.T) {
 	}
 
 	u := Config{
+		ArchiveDir:      "archive",
+		CommitMessage:   "message",
 		FragmentDir:     "fragments",
 		HeaderTemplate:  "header",
 		Hosting:         "hosting",
@@ -38,11 +40,15 @@ func TestConfig_marshal(t *testing.T) {
 		NewsFile:        "news",
 		Repository:      "repo",
 		SectionTemplate: "section",
+		Sources:         []Source{{Type: "command", Command: []string{"fragments", "--json"}}},
+		Trackers:        []Tracker{{Pattern: "^[A-Z]+-\\d+$", URL: "https://jira/browse/{id}"}},
 		Sections: []Section{
 			{
 				Name:       "Name",
 				ShortName:  "name",
 				ShowAlways: func(b bool) *bool { return &b }(true),
+				Bump:       "minor",
+				Skeleton:   "skeleton",
 			},
 		},
 	}
@@ -50,6 +56,8 @@ func TestConfig_marshal(t *testing.T) {
 	wantTOML := `
 # Stentor configuration
 [stentor]
+  archive_dir = "archive"
+  commit_message = "message"
   fragment_dir = "fragments"
   header_template = "header"
   hosting = "hosting"
@@ -59,9 +67,19 @@ func TestConfig_marshal(t *testing.T) {
   section_template = "section"
 
   [[stentor.sections]]
+    bump = "minor"
     name = "Name"
     short_name = "name"
     show_always = true
+    skeleton = "skeleton"
+
+  [[stentor.sources]]
+    command = ["fragments", "--json"]
+    type = "command"
+
+  [[stentor.trackers]]
+    pattern = "^[A-Z]+-\\d+$"
+    url = "https://jira/browse/{id}"
 `
 
 	var v Config
@@ -75,6 +93,81 @@ func TestConfig_marshal(t *testing.T) {
 	}
 }
 
+func TestMarshal(t *testing.T) {
+	c := Config{
+		Hosting:    "gitlab",
+		Markup:     "rst",
+		Repository: "https://gitlab.com/myname/myrepo",
+	}
+
+	if data, err := Marshal(c); assert.NoError(t, err) {
+		want := `# Stentor configuration
+[stentor]
+  hosting = "gitlab"
+  markup = "rst"
+  repository = "https://gitlab.com/myname/myrepo"
+`
+		assert.Equal(t, want, string(data))
+
+		// marshaled data must parse back into an equivalent config
+		if got, err := ParseBytes(data); assert.NoError(t, err) {
+			assert.Equal(t, c.Repository, got.Repository)
+			assert.Equal(t, c.Hosting, got.Hosting)
+			assert.Equal(t, c.Markup, got.Markup)
+		}
+	}
+}
+
+func TestSetValue(t *testing.T) {
+	tests := []struct {
+		name, data, want, wantError string
+	}{
+		{
+			name: "add key",
+			data: "# my config\n[stentor]\nrepository = \"https://host/name/repo\" # the repo\n",
+			want: "# my config\n[stentor]\nsection_template = \"tmpl\"\n" +
+				"repository = \"https://host/name/repo\" # the repo\n",
+		},
+		{
+			name: "add key with indent",
+			data: "[stentor]\n  repository = \"https://host/name/repo\"\n\n  [[stentor.sections]]\n    name = \"A\"\n",
+			want: "[stentor]\n  section_template = \"tmpl\"\n  repository = \"https://host/name/repo\"\n" +
+				"\n  [[stentor.sections]]\n    name = \"A\"\n",
+		},
+		{
+			name: "replace key",
+			data: "[stentor]\n  section_template = \"old\"\nrepository = \"https://host/name/repo\"\n",
+			want: "[stentor]\n  section_template = \"tmpl\"\nrepository = \"https://host/name/repo\"\n",
+		},
+		{
+			name:      "ignore other tables",
+			data:      "[other]\nsection_template = \"old\"\n[stentor]\n",
+			wantError: "undecoded keys: [\"other.section_template\"]",
+		},
+		{
+			name: "no stentor table",
+			data: "",
+			want: "[stentor]\nsection_template = \"tmpl\"\n",
+		},
+		{
+			name: "no trailing newline",
+			data: "# comment",
+			want: "# comment\n[stentor]\nsection_template = \"tmpl\"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SetValue([]byte(tt.data), "section_template", "tmpl")
+			if tt.wantError != "" {
+				assert.EqualError(t, err, tt.wantError)
+			} else if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, string(got))
+			}
+		})
+	}
+}
+
 func Test_parseConfig(t *testing.T) {
 	t.Parallel()
 
@@ -86,30 +179,37 @@ func Test_parseConfig(t *testing.T) {
 		Hosting:     "github",
 		Markup:      "markdown",
 		NewsFile:    "CHANGELOG.md",
+		Sources:     []Source{{Type: "files"}},
 		Sections: []Section{
 			{
 				Name:      "Security",
 				ShortName: "security",
+				Bump:      "patch",
 			},
 			{
 				Name:      "Deprecated",
 				ShortName: "deprecate",
+				Bump:      "minor",
 			},
 			{
 				Name:      "Removed",
 				ShortName: "remove",
+				Bump:      "major",
 			},
 			{
 				Name:      "Changed",
 				ShortName: "change",
+				Bump:      "major",
 			},
 			{
 				Name:      "Added",
 				ShortName: "feature",
+				Bump:      "minor",
 			},
 			{
 				Name:      "Fixed",
 				ShortName: "fix",
+				Bump:      "patch",
 			},
 		},
 	}
@@ -125,6 +225,28 @@ func Test_parseConfig(t *testing.T) {
 			}
 		})
 
+		t.Run("commits defaults", func(t *testing.T) {
+			t.Parallel()
+
+			if c, err := tf([]byte("[stentor.commits]\n")); assert.NoError(t, err) {
+				assert.Equal(t, &Commits{
+					Types:           map[string]string{"feat": "feature", "fix": "fix"},
+					BreakingSection: "change",
+				}, c.Commits)
+			}
+
+			if c, err := tf([]byte("[[stentor.sources]]\ntype = \"commits\"\n")); assert.NoError(t, err) {
+				assert.Equal(t, []Source{{Type: "commits"}}, c.Sources)
+				assert.Equal(t, "change", c.Commits.BreakingSection)
+			}
+
+			data := "[stentor.commits]\nbreaking_section = \"remove\"\ntypes = { perf = \"fix\" }\n"
+			if c, err := tf([]byte(data)); assert.NoError(t, err) {
+				assert.Equal(t, &Commits{Types: map[string]string{"perf": "fix"}, BreakingSection: "remove"}, c.Commits)
+				assert.Equal(t, []Source{{Type: "files"}, {Type: "commits"}}, c.Sources)
+			}
+		})
+
 		t.Run("bad toml", func(t *testing.T) {
 			t.Parallel()
 
@@ -187,8 +309,114 @@ func Test_validateConfig(t *testing.T) {
 		}
 		assert.EqualError(t, ValidateConfig(c), ErrBadSections.Error())
 	}))
+
+	t.Run("invalid commits", func(t *testing.T) {
+		c := Config{
+			Hosting:    "github",
+			Markup:     "markdown",
+			Repository: "https://host/name/repo",
+			Sections:   []Section{{ShortName: "feature"}, {ShortName: "change"}},
+			Commits:    &Commits{Types: map[string]string{"feat": "feature"}, BreakingSection: "change"},
+		}
+		assert.NoError(t, ValidateConfig(c))
+
+		c.Commits.Types["fix"] = "fix"
+		assert.EqualError(t, ValidateConfig(c), `invalid commits: type fix maps to unknown section "fix"`)
+
+		c.Commits = &Commits{BreakingSection: "breaking"}
+		assert.EqualError(t, ValidateConfig(c), `invalid commits: unknown breaking_section "breaking"`)
+	})
+
+	t.Run("invalid sources", func(t *testing.T) {
+		c := Config{
+			Hosting:    "github",
+			Markup:     "markdown",
+			Repository: "https://host/name/repo",
+			Sections:   []Section{{ShortName: "feature"}},
+			Sources:    []Source{{Type: "files"}, {Type: "command", Command: []string{"fragments"}}},
+		}
+		assert.NoError(t, ValidateConfig(c))
+
+		c.Sources[1].Command = nil
+		assert.EqualError(t, ValidateConfig(c), "invalid source 2: command is required")
+
+		c.Sources[0].Command = []string{"fragments"}
+		assert.EqualError(t, ValidateConfig(c), "invalid source 1: command is only valid for command sources")
+
+		c.Sources = []Source{{Type: "jira"}}
+		assert.EqualError(t, ValidateConfig(c), "invalid source 1: "+ErrBadSourceType.Error())
+	})
+
+	t.Run("invalid urls", func(t *testing.T) {
+		urls := &URLs{
+			Compare:     "{repository}/compare/{previous}...{version}",
+			Issue:       "{repository}/issues/{id}",
+			PullRequest: "{repository}/pulls/{id}",
+			Commit:      "{repository}/commit/{id}",
+			Tag:         "{repository}/tags/{id}",
+		}
+		c := Config{
+			Hosting:    "custom",
+			Markup:     "markdown",
+			Repository: "https://host/name/repo",
+			Sections:   []Section{{ShortName: "feature"}},
+			URLs:       urls,
+		}
+		assert.NoError(t, ValidateConfig(c))
+
+		c.Hosting = "github"
+		assert.EqualError(t, ValidateConfig(c), `invalid urls: only valid with hosting "custom"`)
+
+		c.Hosting = "custom"
+		urls.Tag = ""
+		assert.EqualError(t, ValidateConfig(c), "invalid urls: tag is required")
+
+		c.URLs = nil
+		assert.EqualError(t, ValidateConfig(c), `invalid urls: required with hosting "custom"`)
+	})
+
+	t.Run("invalid trackers", func(t *testing.T) {
+		c := Config{
+			Hosting:    "github",
+			Markup:     "markdown",
+			Repository: "https://host/name/repo",
+			Sections:   []Section{{ShortName: "feature"}},
+			Trackers:   []Tracker{{Pattern: `^[A-Z]+-\d+$`, URL: "https://jira/browse/{id}"}},
+		}
+		assert.NoError(t, ValidateConfig(c))
+
+		c.Trackers[0].URL = "https://jira/browse/"
+		assert.EqualError(t, ValidateConfig(c), "invalid tracker 1: url must contain {id}")
+
+		c.Trackers[0].Pattern = "[A-Z"
+		assert.EqualError(t, ValidateConfig(c),
+			"invalid tracker 1: invalid pattern: error parsing regexp: missing closing ]: `[A-Z`")
+
+		c.Trackers[0].Pattern = ""
+		assert.EqualError(t, ValidateConfig(c), "invalid tracker 1: pattern is required")
+	})
+
+	t.Run("invalid bump", rapid.MakeCheck(func(t *rapid.T) {
+		c := Config{
+			Hosting:    genHosting().Draw(t, "hosting"),
+			Markup:     genMarkup().Draw(t, "markup"),
+			Repository: genRepository().Draw(t, "repository"),
+			Sections: []Section{{
+				ShortName: "fix",
+				Bump:      rapid.StringMatching(`[a-z]+`).Filter(func(s string) bool { return !validBumps[s] }).Draw(t, "bump"),
+			}},
+		}
+		assert.EqualError(t, ValidateConfig(c), "invalid section fix: "+ErrBadBump.Error())
+	}))
 }
 
-func genHosting() *rapid.Generator[string]    { return rapid.SampledFrom([]string{"github", "gitlab"}) }
-func genMarkup() *rapid.Generator[string]     { return rapid.SampledFrom([]string{"markdown", "rst"}) }
+var validBumps = map[string]bool{"major": true, "minor": true, "patch": true}
+
+func genHosting() *rapid.Generator[string] {
+	return rapid.SampledFrom([]string{"github", "gitlab", "bitbucket", "gitea", "forgejo", "azure"})
+}
+
+func genMarkup() *rapid.Generator[string] {
+	return rapid.SampledFrom([]string{"asciidoc", "markdown", "rst"})
+}
 func genRepository() *rapid.Generator[string] { return rapid.Just("https://host/name/repo") }
@@ -16,20 +16,59 @@
 package fragment
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"unicode"
 )
 
+// OrphanPrefix starts the name of an orphan fragment file,
+// which does not refer to any issue, eg. +fix-typos.change.md.
+const OrphanPrefix = "+"
+
+// PullRequestPrefix marks a reference in the name of a fragment file as a pull request,
+// or a merge request on GitLab, eg. !123.
+// A "pr" prefix, eg. pr123, does the same.
+const PullRequestPrefix = "!"
+
+// pullRequestRE matches a pull request reference with a "pr" prefix, and captures its ID.
+var pullRequestRE = regexp.MustCompile(`^(?i:pr)(\d+)$`)
+
 // Fragment represents a single change or other news entry.
 type Fragment struct {
 	// Section is the short name of the section this fragment belongs to.
 	Section string
-	// Issue is the ID of any issues or pull requests to link to.
+	// Issue is the ID of the first issue, or pull request if there are no issues, to link to.
+	// It is kept for custom templates written before Issues.
 	Issue string
+	// Issues are the IDs of all the issues to link to.
+	Issues []string
+	// PullRequests are the IDs of the pull requests, or merge requests on GitLab, to link to.
+	PullRequests []string
+	// Authors are the authors of the change.
+	Authors []string
+	// Component is the part of the project the change affects.
+	Component string
+	// Extra holds any other metadata from the front matter of the fragment file.
+	Extra map[string]interface{}
 	// Text is the content of the change.
 	Text string
+	// Links are the links to the fragment's issues and pull requests.
+	// They are resolved when the fragment is added to a release.
+	Links []Link
+	// Provenance records where the fragment came from.
+	Provenance Provenance
+}
+
+// Link is a link to an issue or pull request.
+type Link struct {
+	// Label is the text of the link, eg. #123.
+	Label string
+	// URL is the target of the link.
+	URL string
 }
 
 // Deprecated: New returns a Fragment and the short name of the section it goes into.
@@ -47,15 +86,34 @@ func New(fn string) (Fragment, error) {
 // A fragment file follows the following naming convention:
 // <issues>.<section>[.<summary>].(md|rst).
 //
+// Issues is a comma-separated list of issue IDs, eg. 123,456.
+// IDs of pull requests, or merge requests on GitLab,
+// are prefixed with "pr" or PullRequestPrefix, eg. pr123 or !123.
+// Orphan fragments replace the issues with OrphanPrefix followed by a non-empty slug,
+// eg. +fix-typos, and have no issue.
 // The summary is optional and is ignored by Parse.
+//
+// A fragment file may start with a block of front matter,
+// either TOML between "+++" lines, or YAML between "---" lines,
+// which can set the issues, pull_requests, authors, component, and extra metadata of the fragment.
+// Issues and pull requests in the front matter are added to those in the file name.
+// The text of the fragment is the rest of the file.
 func Parse(fn string) (*Fragment, error) {
 	parts := strings.Split(filepath.Base(fn), ".")
-	var errMsg string
+	var (
+		errMsg               string
+		issues, pullRequests []string
+	)
+	if len(parts) > 0 && !strings.HasPrefix(parts[0], OrphanPrefix) {
+		issues, pullRequests = splitReferences(parts[0])
+	}
 	switch {
 	case len(parts) < 3:
 		errMsg = "not enough parts"
-	case parts[0] == "":
+	case hasEmpty(issues), hasEmpty(pullRequests):
 		errMsg = "empty issue"
+	case parts[0] == OrphanPrefix:
+		errMsg = "empty orphan slug"
 	case parts[1] == "":
 		errMsg = "empty section"
 	}
@@ -69,11 +127,135 @@ func Parse(fn string) (*Fragment, error) {
 		return nil, err
 	}
 
+	fm, text, err := splitFrontMatter(data)
+	if err != nil {
+		return nil, err
+	}
+
+	issues = appendNew(issues, fm.Issues...)
+	pullRequests = appendNew(pullRequests, fm.PullRequests...)
+
 	f := &Fragment{
-		Issue:   parts[0],
-		Section: parts[1],
-		Text:    strings.TrimSpace(string(data)),
+		Issues:       issues,
+		PullRequests: pullRequests,
+		Authors:      fm.Authors,
+		Component:    fm.Component,
+		Extra:        fm.Extra,
+		Section:      parts[1],
+		Text:         strings.TrimSpace(string(text)),
+	}
+	switch {
+	case len(issues) > 0:
+		f.Issue = issues[0]
+	case len(pullRequests) > 0:
+		f.Issue = pullRequests[0]
 	}
 
 	return f, nil
 }
+
+// splitReferences splits a comma-separated list of references
+// into the IDs of issues and of pull requests.
+func splitReferences(s string) (issues, pullRequests []string) {
+	for _, ref := range strings.Split(s, ",") {
+		switch m := pullRequestRE.FindStringSubmatch(ref); {
+		case strings.HasPrefix(ref, PullRequestPrefix):
+			pullRequests = append(pullRequests, strings.TrimPrefix(ref, PullRequestPrefix))
+		case m != nil:
+			pullRequests = append(pullRequests, m[1])
+		default:
+			issues = append(issues, ref)
+		}
+	}
+	return issues, pullRequests
+}
+
+// hasEmpty returns true if any of ss is empty.
+func hasEmpty(ss []string) bool {
+	for _, s := range ss {
+		if s == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Filename returns the name of a fragment file for the issue and section.
+//
+// The issue may be a comma-separated list of issue IDs.
+// If summary is not empty, it is reduced to a lowercase, dash-separated slug
+// and included in the name, following the naming convention described by Parse.
+// The extension ext must include the leading dot.
+func Filename(issue, section, summary, ext string) (string, error) {
+	switch {
+	case hasEmpty(strings.Split(issue, ",")):
+		return "", errors.New("empty issue")
+	case section == "":
+		return "", errors.New("empty section")
+	case strings.ContainsAny(issue, `./\`):
+		return "", fmt.Errorf("invalid issue %q: must not contain '.' or path separators", issue)
+	case strings.ContainsAny(section, `./\`):
+		return "", fmt.Errorf("invalid section %q: must not contain '.' or path separators", section)
+	}
+
+	parts := []string{issue, section}
+	if slug := slugify(summary); slug != "" {
+		parts = append(parts, slug)
+	}
+
+	return strings.Join(parts, ".") + ext, nil
+}
+
+// appendNew appends the elements of vs that are not already in ss to ss.
+func appendNew(ss []string, vs ...string) []string {
+	for _, v := range vs {
+		if !contains(ss, v) {
+			ss = append(ss, v)
+		}
+	}
+	return ss
+}
+
+// contains returns true if ss contains s.
+func contains(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// OrphanFilename returns the name of an orphan fragment file for the section,
+// named after a slug of summary.
+func OrphanFilename(section, summary, ext string) (string, error) {
+	slug := slugify(summary)
+	switch {
+	case slug == "":
+		return "", errors.New("empty summary")
+	case section == "":
+		return "", errors.New("empty section")
+	case strings.ContainsAny(section, `./\`):
+		return "", fmt.Errorf("invalid section %q: must not contain '.' or path separators", section)
+	}
+
+	return OrphanPrefix + slug + "." + section + ext, nil
+}
+
+// slugify converts s into a lowercase string of letters and digits separated by single dashes.
+func slugify(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return b.String()
+}
@@ -17,6 +17,7 @@ package fragment
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -24,13 +25,30 @@ import (
 )
 
 func TestParse(t *testing.T) {
+	ticketFragment := Fragment{Section: "section", Issue: "ticket", Issues: []string{"ticket"}, Text: "contents"}
+
 	tests := []struct {
 		name string
 		want Fragment
 	}{
-		{"ticket.section.md", Fragment{"section", "ticket", "contents"}},
-		{"ticket.section.extra-bit.md", Fragment{"section", "ticket", "contents"}},
-		{"ticket.section.several.extra.bits.md", Fragment{"section", "ticket", "contents"}},
+		{"ticket.section.md", ticketFragment},
+		{"ticket.section.extra-bit.md", ticketFragment},
+		{"ticket.section.several.extra.bits.md", ticketFragment},
+		{"123,456.fix.md", Fragment{Section: "fix", Issue: "123", Issues: []string{"123", "456"}, Text: "contents"}},
+		{"+fix-typos.change.md", Fragment{Section: "change", Text: "contents"}},
+		{"pr123.fix.md", Fragment{Section: "fix", Issue: "123", PullRequests: []string{"123"}, Text: "contents"}},
+		{"!123.fix.md", Fragment{Section: "fix", Issue: "123", PullRequests: []string{"123"}, Text: "contents"}},
+		{
+			"12,PR34,!56,project-7.fix.md",
+			Fragment{
+				Section:      "fix",
+				Issue:        "12",
+				Issues:       []string{"12", "project-7"},
+				PullRequests: []string{"34", "56"},
+				Text:         "contents",
+			},
+		},
+		{"+typos.change.extra-bit.md", Fragment{Section: "change", Text: "contents"}},
 	}
 
 	for _, tt := range tests {
@@ -59,7 +77,11 @@ func TestNew_error(t *testing.T) {
 		{"foo", "not a valid fragment file: not enough parts"},
 		{"foo.md", "not a valid fragment file: not enough parts"},
 		{".section.md", "not a valid fragment file: empty issue"},
+		{"123,.section.md", "not a valid fragment file: empty issue"},
+		{"123,!.section.md", "not a valid fragment file: empty issue"},
 		{"ticket..md", "not a valid fragment file: empty section"},
+		{"+orphan..md", "not a valid fragment file: empty section"},
+		{"+.fix.md", "not a valid fragment file: empty orphan slug"},
 	}
 
 	for _, tt := range tests {
@@ -74,3 +96,73 @@ func TestNew_error(t *testing.T) {
 		})
 	}
 }
+
+func TestFilename(t *testing.T) {
+	tests := []struct {
+		issue, section, summary, ext string
+		want, wantError              string
+	}{
+		{"123", "fix", "", ".md", "123.fix.md", ""},
+		{"123", "fix", "Short summary", ".rst", "123.fix.short-summary.rst", ""},
+		{"123", "fix", "  Don't crash on `foo.bar`!  ", ".md", "123.fix.don-t-crash-on-foo-bar.md", ""},
+		{"123", "fix", "...", ".md", "123.fix.md", ""},
+		{"123,456", "fix", "", ".md", "123,456.fix.md", ""},
+		{"", "fix", "", ".md", "", "empty issue"},
+		{"123,", "fix", "", ".md", "", "empty issue"},
+		{"123", "", "", ".md", "", "empty section"},
+		{"1.2", "fix", "", ".md", "", `invalid issue "1.2": must not contain '.' or path separators`},
+		{"../1", "fix", "", ".md", "", `invalid issue "../1": must not contain '.' or path separators`},
+		{"123", "f/x", "", ".md", "", `invalid section "f/x": must not contain '.' or path separators`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want+tt.wantError, func(t *testing.T) {
+			got, err := Filename(tt.issue, tt.section, tt.summary, tt.ext)
+			if tt.wantError != "" {
+				assert.EqualError(t, err, tt.wantError)
+			} else if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, got)
+
+				// the generated name must round trip through Parse
+				fn := filepath.Join(t.TempDir(), got)
+				require.NoError(t, os.WriteFile(fn, []byte(`contents`), 0600))
+				if f, err := Parse(fn); assert.NoError(t, err) {
+					assert.Equal(t, strings.Split(tt.issue, ","), f.Issues)
+					assert.Equal(t, tt.section, f.Section)
+				}
+			}
+		})
+	}
+}
+
+func TestOrphanFilename(t *testing.T) {
+	tests := []struct {
+		section, summary, ext string
+		want, wantError       string
+	}{
+		{"change", "Fix typos", ".md", "+fix-typos.change.md", ""},
+		{"change", "Reword the README.", ".rst", "+reword-the-readme.change.rst", ""},
+		{"change", "...", ".md", "", "empty summary"},
+		{"", "Fix typos", ".md", "", "empty section"},
+		{"c.ange", "Fix typos", ".md", "", `invalid section "c.ange": must not contain '.' or path separators`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want+tt.wantError, func(t *testing.T) {
+			got, err := OrphanFilename(tt.section, tt.summary, tt.ext)
+			if tt.wantError != "" {
+				assert.EqualError(t, err, tt.wantError)
+			} else if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, got)
+
+				// the generated name must parse as an orphan
+				fn := filepath.Join(t.TempDir(), got)
+				require.NoError(t, os.WriteFile(fn, []byte(`contents`), 0600))
+				if f, err := Parse(fn); assert.NoError(t, err) {
+					assert.Empty(t, f.Issues)
+					assert.Equal(t, tt.section, f.Section)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright © 2020 The Stentor Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fragment
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Front matter delimiters.
+const (
+	tomlDelimiter = "+++"
+	yamlDelimiter = "---"
+)
+
+// frontMatter is the metadata block at the top of a fragment file.
+type frontMatter struct {
+	// Issues and PullRequests are decoded from RawIssues and RawPullRequests,
+	// which may hold strings or integers, like issues = [12].
+	Issues          []string               `toml:"-" yaml:"-"`
+	PullRequests    []string               `toml:"-" yaml:"-"`
+	RawIssues       []interface{}          `toml:"issues" yaml:"issues"`
+	RawPullRequests []interface{}          `toml:"pull_requests" yaml:"pull_requests"`
+	Authors         []string               `toml:"authors" yaml:"authors"`
+	Component       string                 `toml:"component" yaml:"component"`
+	Extra           map[string]interface{} `toml:"extra" yaml:"extra"`
+}
+
+// splitFrontMatter splits data into its front matter and the text that follows it.
+//
+// Front matter is a TOML block delimited by "+++" lines,
+// or a YAML mapping delimited by "---" lines,
+// starting on the first line of data.
+// A "---" line that does not start a YAML mapping is a thematic break, not front matter.
+// If data has no front matter, the front matter is empty and the text is all of data.
+func splitFrontMatter(data []byte) (frontMatter, []byte, error) {
+	var fm frontMatter
+
+	lines := strings.SplitAfter(string(data), "\n")
+	delimiter := strings.TrimRight(lines[0], "\r\n")
+	if delimiter != tomlDelimiter && delimiter != yamlDelimiter {
+		return fm, data, nil
+	}
+
+	end := -1
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimRight(lines[i], "\r\n") == delimiter {
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		if delimiter == yamlDelimiter {
+			return fm, data, nil
+		}
+		return fm, nil, errors.New("invalid front matter: no closing " + delimiter)
+	}
+
+	block := []byte(strings.Join(lines[1:end], ""))
+	text := []byte(strings.Join(lines[end+1:], ""))
+
+	var err error
+	if delimiter == tomlDelimiter {
+		err = toml.NewDecoder(bytes.NewReader(block)).Strict(true).Decode(&fm)
+	} else {
+		if !isYAMLMapping(block) {
+			return fm, data, nil
+		}
+
+		dec := yaml.NewDecoder(bytes.NewReader(block))
+		dec.KnownFields(true)
+		// an empty block is io.EOF, which leaves fm empty
+		if err = dec.Decode(&fm); err != nil && len(bytes.TrimSpace(block)) == 0 {
+			err = nil
+		}
+	}
+	if err != nil {
+		return fm, nil, fmt.Errorf("invalid front matter: %w", err)
+	}
+
+	if fm.Issues, err = ids(fm.RawIssues); err != nil {
+		return fm, nil, fmt.Errorf("invalid front matter: %w", err)
+	}
+	if fm.PullRequests, err = ids(fm.RawPullRequests); err != nil {
+		return fm, nil, fmt.Errorf("invalid front matter: %w", err)
+	}
+
+	if hasEmpty(fm.Issues) || hasEmpty(fm.PullRequests) {
+		return fm, nil, errors.New("invalid front matter: empty issue")
+	}
+
+	return fm, text, nil
+}
+
+// isYAMLMapping returns true if block is empty, or is a YAML mapping.
+func isYAMLMapping(block []byte) bool {
+	var node yaml.Node
+	if err := yaml.Unmarshal(block, &node); err != nil {
+		return false
+	}
+
+	// an empty block has no document
+	return len(node.Content) == 0 || node.Content[0].Kind == yaml.MappingNode
+}
+
+// ids returns the issue IDs in values, which are strings or integers.
+func ids(values []interface{}) ([]string, error) {
+	if values == nil {
+		return nil, nil
+	}
+
+	ids := make([]string, 0, len(values))
+	for _, v := range values {
+		switch v := v.(type) {
+		case string:
+			ids = append(ids, v)
+		case int:
+			ids = append(ids, strconv.Itoa(v))
+		case int64:
+			ids = append(ids, strconv.FormatInt(v, 10))
+		case uint64:
+			ids = append(ids, strconv.FormatUint(v, 10))
+		default:
+			return nil, fmt.Errorf("issue %v must be a string or an integer", v)
+		}
+	}
+
+	return ids, nil
+}
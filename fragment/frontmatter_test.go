@@ -0,0 +1,153 @@
+// Copyright © 2020 The Stentor Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fragment
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse_frontMatter(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		want     Fragment
+		wantErr  string
+	}{
+		{
+			name: "12.fix.md",
+			contents: "+++\nissues = [\"12\", \"34\"]\npull_requests = [\"56\"]\nauthors = [\"alice\"]\n" +
+				"component = \"parser\"\n\n[extra]\nticket = \"SUP-1\"\n+++\n\nFixed the parser.\n",
+			want: Fragment{
+				Section:      "fix",
+				Issue:        "12",
+				Issues:       []string{"12", "34"},
+				PullRequests: []string{"56"},
+				Authors:      []string{"alice"},
+				Component:    "parser",
+				Extra:        map[string]interface{}{"ticket": "SUP-1"},
+				Text:         "Fixed the parser.",
+			},
+		},
+		{
+			name:     "!56.fix.md",
+			contents: "+++\npull_requests = [\"56\", \"78\"]\n+++\nFixed the parser.\n",
+			want: Fragment{
+				Section:      "fix",
+				Issue:        "56",
+				PullRequests: []string{"56", "78"},
+				Text:         "Fixed the parser.",
+			},
+		},
+		{
+			name: "+parser.fix.md",
+			contents: "---\r\nissues: [12]\r\nauthors:\r\n  - alice\r\n  - bob\r\n" +
+				"extra:\r\n  breaking: true\r\n---\r\nFixed the parser.\r\n",
+			want: Fragment{
+				Section: "fix",
+				Issue:   "12",
+				Issues:  []string{"12"},
+				Authors: []string{"alice", "bob"},
+				Extra:   map[string]interface{}{"breaking": true},
+				Text:    "Fixed the parser.",
+			},
+		},
+		{
+			name:     "12.fix.md",
+			contents: "---\n---\nFixed the parser.\n\n---\n\nDetails.\n",
+			want: Fragment{
+				Section: "fix",
+				Issue:   "12",
+				Issues:  []string{"12"},
+				Text:    "Fixed the parser.\n\n---\n\nDetails.",
+			},
+		},
+		{
+			name:     "+parser.fix.md",
+			contents: "+++\nissues = [12, \"34\"]\npull_requests = [56]\n+++\nFixed the parser.\n",
+			want: Fragment{
+				Section:      "fix",
+				Issue:        "12",
+				Issues:       []string{"12", "34"},
+				PullRequests: []string{"56"},
+				Text:         "Fixed the parser.",
+			},
+		},
+		{
+			name:     "12.fix.md",
+			contents: "---\n\nFixed the parser.\n\n---\n\nDetails.\n",
+			want: Fragment{
+				Section: "fix",
+				Issue:   "12",
+				Issues:  []string{"12"},
+				Text:    "---\n\nFixed the parser.\n\n---\n\nDetails.",
+			},
+		},
+		{
+			name:     "12.fix.md",
+			contents: "---\n\nFixed the parser.\n",
+			want:     Fragment{Section: "fix", Issue: "12", Issues: []string{"12"}, Text: "---\n\nFixed the parser."},
+		},
+		{
+			name:     "12.fix.md",
+			contents: "Fixed the parser.\n+++\n",
+			want:     Fragment{Section: "fix", Issue: "12", Issues: []string{"12"}, Text: "Fixed the parser.\n+++"},
+		},
+		{
+			name:     "12.fix.md",
+			contents: "+++\nissues = [\"12\"]\nFixed the parser.\n",
+			wantErr:  "invalid front matter: no closing +++",
+		},
+		{
+			name:     "12.fix.md",
+			contents: "+++\nowner = \"alice\"\n+++\nFixed the parser.\n",
+			wantErr:  "invalid front matter: undecoded keys: [\"owner\"]",
+		},
+		{
+			name:     "12.fix.md",
+			contents: "---\nowner: alice\n---\nFixed the parser.\n",
+			wantErr: "invalid front matter: yaml: unmarshal errors:\n" +
+				"  line 1: field owner not found in type fragment.frontMatter",
+		},
+		{
+			name:     "12.fix.md",
+			contents: "+++\nissues = [1.5]\n+++\nFixed the parser.\n",
+			wantErr:  "invalid front matter: issue 1.5 must be a string or an integer",
+		},
+		{
+			name:     "12.fix.md",
+			contents: "---\nissues: [\"\"]\n---\nFixed the parser.\n",
+			wantErr:  "invalid front matter: empty issue",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fn := filepath.Join(t.TempDir(), tt.name)
+			require.NoError(t, os.WriteFile(fn, []byte(tt.contents), 0600))
+
+			got, err := Parse(fn)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, *got)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright © 2020 The Stentor Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fragment
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Types of fragment sources.
+const (
+	SourceCommand = "command"
+	SourceCommits = "commits"
+	SourceFiles   = "files"
+)
+
+// Provenance records where a fragment came from.
+type Provenance struct {
+	// Source is the type of the source of the fragment, eg. files.
+	Source string
+	// Ref identifies the fragment within its source,
+	// eg. the path of a fragment file, or the hash of a commit.
+	Ref string
+}
+
+// FragmentSource is a source of fragments.
+type FragmentSource interface {
+	// Fragments returns the fragments for the release that follows previous.
+	Fragments(previous string) ([]Fragment, error)
+}
+
+// FileSource reads fragments from the fragment files in a directory.
+type FileSource struct {
+	// Dir is the directory holding the fragment files.
+	Dir string
+	// Ext is the extension of fragment files, including the leading dot.
+	Ext string
+	// Invalid is called with the name of each invalid fragment file and the reason it is invalid.
+	// Invalid files are skipped.
+	Invalid func(fn string, err error)
+}
+
+// Fragments returns the fragments parsed from the fragment files in s.Dir.
+//
+// The Ref of each fragment is the path of its file.
+func (s FileSource) Fragments(string) ([]Fragment, error) {
+	files, err := filepath.Glob(filepath.Join(s.Dir, "*"+s.Ext))
+	if err != nil {
+		return nil, err
+	}
+
+	var fragments []Fragment
+	for _, fn := range files {
+		f, err := Parse(fn)
+		if err != nil {
+			if s.Invalid != nil {
+				s.Invalid(fn, err)
+			}
+			continue
+		}
+
+		f.Provenance = Provenance{Source: SourceFiles, Ref: fn}
+		fragments = append(fragments, *f)
+	}
+
+	return fragments, nil
+}
+
+// CommandSource reads fragments from the output of a command.
+//
+// The command is run with the STENTOR_PREVIOUS environment variable set to the previous version,
+// which is empty for the first release,
+// and must write a JSON array of fragments to stdout,
+// eg. [{"section": "fix", "issues": ["12", "13"], "text": "Fixed the foo."}].
+// A single issue may also be given as "issue",
+// and pull requests as "pull_requests".
+type CommandSource struct {
+	// Command is the command to run, and its arguments.
+	Command []string
+	// Dir is the directory the command is run in.
+	Dir string
+	// Env is the environment the command is run with.
+	// If nil, the command inherits the current process's environment.
+	Env []string
+}
+
+// commandFragment is a fragment written by a command.
+type commandFragment struct {
+	Section      string   `json:"section"`
+	Issue        string   `json:"issue"`
+	Issues       []string `json:"issues"`
+	PullRequests []string `json:"pull_requests"`
+	Text         string   `json:"text"`
+}
+
+// Fragments runs the command and returns the fragments it writes.
+//
+// The Ref of each fragment is the command and its arguments.
+func (s CommandSource) Fragments(previous string) ([]Fragment, error) {
+	if len(s.Command) == 0 {
+		return nil, errors.New("empty command")
+	}
+
+	name := s.Command[0]
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(s.Command[0], s.Command[1:]...)
+	cmd.Dir = s.Dir
+	cmd.Env = s.Env
+	if cmd.Env == nil {
+		cmd.Env = cmd.Environ()
+	}
+	cmd.Env = append(cmd.Env, "STENTOR_PREVIOUS="+previous)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("cannot run %s: %w: %s", name, err, msg)
+		}
+		return nil, fmt.Errorf("cannot run %s: %w", name, err)
+	}
+
+	var output []commandFragment
+	if err := json.Unmarshal(stdout.Bytes(), &output); err != nil {
+		return nil, fmt.Errorf("invalid output from %s: %w", name, err)
+	}
+
+	fragments := make([]Fragment, 0, len(output))
+	for i, f := range output {
+		if f.Section == "" {
+			return nil, fmt.Errorf("invalid output from %s: fragment %d has no section", name, i+1)
+		}
+
+		issues := f.Issues
+		if len(issues) == 0 && f.Issue != "" {
+			issues = []string{f.Issue}
+		}
+		if hasEmpty(issues) || hasEmpty(f.PullRequests) {
+			return nil, fmt.Errorf("invalid output from %s: fragment %d has an empty issue", name, i+1)
+		}
+
+		fragment := Fragment{
+			Section:      f.Section,
+			Issues:       issues,
+			PullRequests: f.PullRequests,
+			Text:         strings.TrimSpace(f.Text),
+			Provenance:   Provenance{Source: SourceCommand, Ref: strings.Join(s.Command, " ")},
+		}
+		switch {
+		case len(issues) > 0:
+			fragment.Issue = issues[0]
+		case len(f.PullRequests) > 0:
+			fragment.Issue = f.PullRequests[0]
+		}
+		fragments = append(fragments, fragment)
+	}
+
+	return fragments, nil
+}
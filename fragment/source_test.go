@@ -0,0 +1,116 @@
+// Copyright © 2020 The Stentor Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fragment
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileSource_Fragments(t *testing.T) {
+	dir := t.TempDir()
+	for fn, text := range map[string]string{"1.fix.md": "A fix.", "2.feature.md": "A feature.", "3.fix.rst": "Ignored."} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, fn), []byte(text), 0600))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "bad.md"), []byte("Invalid."), 0600))
+
+	var invalid []string
+	s := FileSource{Dir: dir, Ext: ".md", Invalid: func(fn string, err error) { invalid = append(invalid, fn) }}
+
+	if got, err := s.Fragments("v1.0.0"); assert.NoError(t, err) {
+		assert.Equal(t, []Fragment{
+			{
+				Section:    "fix",
+				Issue:      "1",
+				Issues:     []string{"1"},
+				Text:       "A fix.",
+				Provenance: Provenance{Source: SourceFiles, Ref: filepath.Join(dir, "1.fix.md")},
+			},
+			{
+				Section:    "feature",
+				Issue:      "2",
+				Issues:     []string{"2"},
+				Text:       "A feature.",
+				Provenance: Provenance{Source: SourceFiles, Ref: filepath.Join(dir, "2.feature.md")},
+			},
+		}, got)
+		assert.Equal(t, []string{filepath.Join(dir, "bad.md")}, invalid)
+	}
+}
+
+func TestCommandSource_Fragments(t *testing.T) {
+	script := `printf '[{"section": "fix", "issue": "%s", "text": " A fix. "}, ` +
+		`{"section": "feature", "issues": ["1", "2"]}, {"section": "fix", "pull_requests": ["3"]}]' ` +
+		`"$STENTOR_PREVIOUS"`
+	provenance := Provenance{Source: SourceCommand, Ref: "sh -c " + script}
+
+	tests := []struct {
+		name    string
+		script  string
+		want    []Fragment
+		wantErr string
+	}{
+		{
+			name:   "fragments",
+			script: script,
+			want: []Fragment{
+				{Section: "fix", Issue: "v1.0.0", Issues: []string{"v1.0.0"}, Text: "A fix.", Provenance: provenance},
+				{Section: "feature", Issue: "1", Issues: []string{"1", "2"}, Provenance: provenance},
+				{Section: "fix", Issue: "3", PullRequests: []string{"3"}, Provenance: provenance},
+			},
+		},
+		{
+			name:   "no fragments",
+			script: `echo '[]'`,
+			want:   []Fragment{},
+		},
+		{
+			name:    "failure",
+			script:  `echo 'no tracker' >&2; exit 3`,
+			wantErr: "cannot run sh: exit status 3: no tracker",
+		},
+		{
+			name:    "invalid output",
+			script:  `echo '{}'`,
+			wantErr: "invalid output from sh: json: cannot unmarshal object into Go value of type []fragment.commandFragment",
+		},
+		{
+			name:    "missing section",
+			script:  `echo '[{"text": "A fix."}]'`,
+			wantErr: "invalid output from sh: fragment 1 has no section",
+		},
+		{
+			name:    "empty issue",
+			script:  `echo '[{"section": "fix", "issues": ["1", ""]}]'`,
+			wantErr: "invalid output from sh: fragment 1 has an empty issue",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := CommandSource{Command: []string{"sh", "-c", tt.script}, Dir: t.TempDir()}
+			got, err := s.Fragments("v1.0.0")
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
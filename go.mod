@@ -3,13 +3,15 @@ module github.com/wfscheper/stentor
 go 1.23
 
 require (
+	github.com/mattn/go-runewidth v0.0.16
 	github.com/pelletier/go-toml v1.9.5
+	github.com/pmezard/go-difflib v1.0.0
 	github.com/stretchr/testify v1.11.1
+	gopkg.in/yaml.v3 v3.0.1
 	pgregory.net/rapid v1.3.0
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
-	github.com/pmezard/go-difflib v1.0.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	github.com/rivo/uniseg v0.2.0 // indirect
 )
@@ -0,0 +1,162 @@
+// Copyright © 2020 The Stentor Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package hosting builds the URLs of the pages of a repository's hosting provider.
+package hosting
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/wfscheper/stentor"
+	"github.com/wfscheper/stentor/config"
+)
+
+// Provider builds the URLs of a repository's pages on its hosting provider.
+type Provider interface {
+	// CompareURL returns the URL of the changes between the versions previous and version.
+	CompareURL(previous, version string) string
+	// IssueURL returns the URL of the issue id.
+	IssueURL(id string) string
+	// PullRequestURL returns the URL of the pull request id.
+	PullRequestURL(id string) string
+	// PullRequestLabel returns the label of links to the pull request id, eg. #12.
+	PullRequestLabel(id string) string
+	// CommitURL returns the URL of the commit hash.
+	CommitURL(hash string) string
+	// TagURL returns the URL of the tag.
+	TagURL(tag string) string
+}
+
+// dotSegmentRE matches paths with "." or ".." segments.
+var dotSegmentRE = regexp.MustCompile(`(^|/)\.\.?(/|$)`)
+
+// providers are the URL patterns of the built-in hosting providers.
+var providers = map[string]config.URLs{
+	// Azure Repos, eg. https://dev.azure.com/org/project/_git/repo
+	stentor.HostingAzure: {
+		Compare:           "{repository}/branchCompare?baseVersion=GT{previous}&targetVersion=GT{version}",
+		Issue:             "{repository}/../../_workitems/edit/{id}",
+		PullRequest:       "{repository}/pullrequest/{id}",
+		PullRequestPrefix: "!",
+		Commit:            "{repository}/commit/{id}",
+		Tag:               "{repository}?version=GT{id}",
+	},
+	// Bitbucket Server and Data Center, eg. https://bitbucket.example.com/projects/PROJ/repos/repo,
+	// which have no issue tracker of their own, so issues are not linked
+	stentor.HostingBitbucket: {
+		Compare: "{repository}/compare/commits?" +
+			"sourceBranch=refs%2Ftags%2F{version}&targetBranch=refs%2Ftags%2F{previous}",
+		PullRequest: "{repository}/pull-requests/{id}",
+		Commit:      "{repository}/commits/{id}",
+		Tag:         "{repository}/browse?at=refs%2Ftags%2F{id}",
+	},
+	stentor.HostingForgejo: giteaURLs,
+	stentor.HostingGitea:   giteaURLs,
+	stentor.HostingGithub: {
+		Compare:     "{repository}/compare/{previous}...{version}",
+		Issue:       "{repository}/issues/{id}",
+		PullRequest: "{repository}/pull/{id}",
+		Commit:      "{repository}/commit/{id}",
+		Tag:         "{repository}/releases/tag/{id}",
+	},
+	stentor.HostingGitlab: {
+		Compare:           "{repository}/-/compare/{previous}...{version}",
+		Issue:             "{repository}/-/issues/{id}",
+		PullRequest:       "{repository}/-/merge_requests/{id}",
+		PullRequestPrefix: "!",
+		Commit:            "{repository}/-/commit/{id}",
+		Tag:               "{repository}/-/tags/{id}",
+	},
+}
+
+// giteaURLs are the URL patterns of Gitea, and its fork Forgejo.
+var giteaURLs = config.URLs{
+	Compare:     "{repository}/compare/{previous}...{version}",
+	Issue:       "{repository}/issues/{id}",
+	PullRequest: "{repository}/pulls/{id}",
+	Commit:      "{repository}/commit/{id}",
+	Tag:         "{repository}/releases/tag/{id}",
+}
+
+// New returns the Provider for the repository hosted by hosting.
+//
+// The URL patterns of custom hosting come from urls,
+// which is ignored by the built-in hosting providers.
+func New(hosting, repository string, urls *config.URLs) (Provider, error) {
+	var patterns config.URLs
+	switch p, ok := providers[hosting]; {
+	case ok:
+		patterns = p
+	case hosting == stentor.HostingCustom && urls != nil:
+		patterns = *urls
+	case hosting == stentor.HostingCustom:
+		return nil, fmt.Errorf("hosting %q requires urls", hosting)
+	default:
+		return nil, fmt.Errorf("unknown hosting %q", hosting)
+	}
+
+	if patterns.PullRequestPrefix == "" {
+		patterns.PullRequestPrefix = "#"
+	}
+
+	return patternProvider{repository: repository, patterns: patterns}, nil
+}
+
+// patternProvider is a Provider that builds URLs from patterns.
+type patternProvider struct {
+	repository string
+	patterns   config.URLs
+}
+
+func (p patternProvider) CompareURL(previous, version string) string {
+	return p.expand(p.patterns.Compare, "{previous}", previous, "{version}", version)
+}
+
+func (p patternProvider) IssueURL(id string) string {
+	return p.expand(p.patterns.Issue, "{id}", id)
+}
+
+func (p patternProvider) PullRequestURL(id string) string {
+	return p.expand(p.patterns.PullRequest, "{id}", id)
+}
+
+func (p patternProvider) PullRequestLabel(id string) string {
+	return p.patterns.PullRequestPrefix + id
+}
+
+func (p patternProvider) CommitURL(hash string) string {
+	return p.expand(p.patterns.Commit, "{id}", hash)
+}
+
+func (p patternProvider) TagURL(tag string) string {
+	return p.expand(p.patterns.Tag, "{id}", tag)
+}
+
+// expand replaces {repository}, and the placeholder and value pairs in oldnew, in pattern.
+//
+// Any "." or ".." segments in the path of the expanded URL are resolved,
+// so patterns can refer to pages outside the repository.
+func (p patternProvider) expand(pattern string, oldnew ...string) string {
+	s := strings.NewReplacer(append([]string{"{repository}", p.repository}, oldnew...)...).Replace(pattern)
+
+	u, err := url.Parse(s)
+	if err != nil || !dotSegmentRE.MatchString(u.EscapedPath()) {
+		return s
+	}
+
+	return u.ResolveReference(&url.URL{}).String()
+}
@@ -0,0 +1,142 @@
+// Copyright © 2020 The Stentor Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hosting
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wfscheper/stentor/config"
+)
+
+func TestNew(t *testing.T) {
+	type urls struct {
+		compare, issue, pullRequest, pullRequestLabel, commit, tag string
+	}
+
+	tests := []struct {
+		hosting string
+		repo    string
+		urls    *config.URLs
+		want    urls
+	}{
+		{
+			hosting: "azure",
+			repo:    "https://dev.azure.com/org/project/_git/repo",
+			want: urls{
+				compare: "https://dev.azure.com/org/project/_git/repo/branchCompare?" +
+					"baseVersion=GTv1.0.0&targetVersion=GTv1.1.0",
+				issue:            "https://dev.azure.com/org/project/_workitems/edit/12",
+				pullRequest:      "https://dev.azure.com/org/project/_git/repo/pullrequest/34",
+				pullRequestLabel: "!34",
+				commit:           "https://dev.azure.com/org/project/_git/repo/commit/abc123",
+				tag:              "https://dev.azure.com/org/project/_git/repo?version=GTv1.1.0",
+			},
+		},
+		{
+			hosting: "bitbucket",
+			repo:    "https://bitbucket.example.com/projects/PROJ/repos/repo",
+			want: urls{
+				compare: "https://bitbucket.example.com/projects/PROJ/repos/repo/compare/commits?" +
+					"sourceBranch=refs%2Ftags%2Fv1.1.0&targetBranch=refs%2Ftags%2Fv1.0.0",
+				issue:            "",
+				pullRequest:      "https://bitbucket.example.com/projects/PROJ/repos/repo/pull-requests/34",
+				pullRequestLabel: "#34",
+				commit:           "https://bitbucket.example.com/projects/PROJ/repos/repo/commits/abc123",
+				tag:              "https://bitbucket.example.com/projects/PROJ/repos/repo/browse?at=refs%2Ftags%2Fv1.1.0",
+			},
+		},
+		{
+			hosting: "forgejo",
+			want: urls{
+				compare:          "https://host/name/repo/compare/v1.0.0...v1.1.0",
+				issue:            "https://host/name/repo/issues/12",
+				pullRequest:      "https://host/name/repo/pulls/34",
+				pullRequestLabel: "#34",
+				commit:           "https://host/name/repo/commit/abc123",
+				tag:              "https://host/name/repo/releases/tag/v1.1.0",
+			},
+		},
+		{
+			hosting: "github",
+			want: urls{
+				compare:          "https://host/name/repo/compare/v1.0.0...v1.1.0",
+				issue:            "https://host/name/repo/issues/12",
+				pullRequest:      "https://host/name/repo/pull/34",
+				pullRequestLabel: "#34",
+				commit:           "https://host/name/repo/commit/abc123",
+				tag:              "https://host/name/repo/releases/tag/v1.1.0",
+			},
+		},
+		{
+			hosting: "gitlab",
+			// urls are ignored by built-in providers
+			urls: &config.URLs{Compare: "{repository}/ignored"},
+			want: urls{
+				compare:          "https://host/name/repo/-/compare/v1.0.0...v1.1.0",
+				issue:            "https://host/name/repo/-/issues/12",
+				pullRequest:      "https://host/name/repo/-/merge_requests/34",
+				pullRequestLabel: "!34",
+				commit:           "https://host/name/repo/-/commit/abc123",
+				tag:              "https://host/name/repo/-/tags/v1.1.0",
+			},
+		},
+		{
+			hosting: "custom",
+			urls: &config.URLs{
+				Compare:     "{repository}/diff?from={previous}&to={version}",
+				Issue:       "https://tracker/{id}",
+				PullRequest: "{repository}/reviews/{id}",
+				Commit:      "{repository}/rev/{id}",
+				Tag:         "{repository}/tags/{id}",
+			},
+			want: urls{
+				compare:          "https://host/name/repo/diff?from=v1.0.0&to=v1.1.0",
+				issue:            "https://tracker/12",
+				pullRequest:      "https://host/name/repo/reviews/34",
+				pullRequestLabel: "#34",
+				commit:           "https://host/name/repo/rev/abc123",
+				tag:              "https://host/name/repo/tags/v1.1.0",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.hosting, func(t *testing.T) {
+			repo := tt.repo
+			if repo == "" {
+				repo = "https://host/name/repo"
+			}
+			if p, err := New(tt.hosting, repo, tt.urls); assert.NoError(t, err) {
+				assert.Equal(t, tt.want, urls{
+					compare:          p.CompareURL("v1.0.0", "v1.1.0"),
+					issue:            p.IssueURL("12"),
+					pullRequest:      p.PullRequestURL("34"),
+					pullRequestLabel: p.PullRequestLabel("34"),
+					commit:           p.CommitURL("abc123"),
+					tag:              p.TagURL("v1.1.0"),
+				})
+			}
+		})
+	}
+}
+
+func TestNew_error(t *testing.T) {
+	_, err := New("custom", "https://host/name/repo", nil)
+	assert.EqualError(t, err, `hosting "custom" requires urls`)
+
+	_, err = New("forge", "https://host/name/repo", nil)
+	assert.EqualError(t, err, `unknown hosting "forge"`)
+}
@@ -0,0 +1,150 @@
+// Copyright © 2020 The Stentor Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package conventional turns Conventional Commits into fragments.
+//
+// See https://www.conventionalcommits.org/en/v1.0.0/.
+package conventional
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/wfscheper/stentor/config"
+	"github.com/wfscheper/stentor/fragment"
+	"github.com/wfscheper/stentor/internal/git"
+)
+
+// headerRE matches a commit header, eg. "feat(parser)!: add arrays".
+var headerRE = regexp.MustCompile(`^([A-Za-z]+)(?:\(([^()]*)\))?(!)?: +(\S.*)$`)
+
+// issueRE matches an issue reference in a trailer, eg. "#12" or a Jira style key like "PROJ-12",
+// as a whole token in a list separated by commas or spaces,
+// so the numbers in a URL are not issues.
+var issueRE = regexp.MustCompile(`(?:^|[\s,])#?([0-9]+|[A-Z][A-Z0-9]+-[0-9]+)\b`)
+
+// Message is a parsed Conventional Commit message.
+type Message struct {
+	// Type is the commit type, eg. feat.
+	Type string
+	// Scope is the optional scope of the change.
+	Scope string
+	// Description is the summary of the change.
+	Description string
+	// Breaking is true if the commit is a breaking change.
+	Breaking bool
+	// BreakingChange is the description of a breaking change from a BREAKING CHANGE trailer.
+	BreakingChange string
+	// Issues are the issues referenced by Refs or Closes trailers.
+	Issues []string
+}
+
+// Parse parses the message of c.
+//
+// It returns false if the message is not a Conventional Commit.
+func Parse(c git.Commit) (Message, bool) {
+	m := headerRE.FindStringSubmatch(c.Subject())
+	if m == nil {
+		return Message{}, false
+	}
+
+	msg := Message{
+		Type:        strings.ToLower(m[1]),
+		Scope:       m[2],
+		Description: m[4],
+		Breaking:    m[3] == "!",
+	}
+
+	for _, t := range c.Trailers() {
+		switch strings.ToLower(t.Key) {
+		case "breaking change", "breaking-change":
+			msg.Breaking = true
+			msg.BreakingChange = t.Value
+		case "refs", "closes":
+			for _, m := range issueRE.FindAllStringSubmatch(t.Value, -1) {
+				msg.Issues = append(msg.Issues, m[1])
+			}
+		}
+	}
+
+	return msg, true
+}
+
+// Fragments returns fragments for the Conventional Commits in commits.
+//
+// Commits are expected newest first, as returned by git log,
+// and fragments are returned oldest first.
+// Breaking changes go into the breaking section of cfg,
+// and other commits into the section mapped to their type.
+// Commits with unmapped types, and commits that are not Conventional Commits, are ignored.
+func Fragments(commits []git.Commit, cfg config.Commits) []fragment.Fragment {
+	var fragments []fragment.Fragment
+	for i := len(commits) - 1; i >= 0; i-- {
+		msg, ok := Parse(commits[i])
+		if !ok {
+			continue
+		}
+
+		section := cfg.Types[msg.Type]
+		if msg.Breaking {
+			section = cfg.BreakingSection
+		}
+		if section == "" {
+			continue
+		}
+
+		f := fragment.Fragment{
+			Section:    section,
+			Text:       msg.Description,
+			Provenance: fragment.Provenance{Source: fragment.SourceCommits, Ref: commits[i].Hash},
+		}
+		if msg.BreakingChange != "" {
+			f.Text += "\n\n" + msg.BreakingChange
+		}
+		if len(msg.Issues) > 0 {
+			f.Issue, f.Issues = msg.Issues[0], msg.Issues
+		}
+		fragments = append(fragments, f)
+	}
+
+	return fragments
+}
+
+// Source is a fragment.FragmentSource that reads fragments from the Conventional Commits in a git repository.
+type Source struct {
+	// Repo is the git repository to read commits from.
+	Repo git.Repo
+	// Commits configures how commits are turned into fragments.
+	Commits config.Commits
+}
+
+// Fragments returns fragments for the Conventional Commits made since previous,
+// or for all the commits reachable from HEAD if previous is empty.
+func (s Source) Fragments(previous string) ([]fragment.Fragment, error) {
+	if previous == "" {
+		commits, err := s.Repo.Log("HEAD")
+		if err != nil {
+			return nil, fmt.Errorf("cannot read commits: %w", err)
+		}
+		return Fragments(commits, s.Commits), nil
+	}
+
+	commits, err := s.Repo.Log(previous + "..HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("cannot read commits since %s: %w", previous, err)
+	}
+
+	return Fragments(commits, s.Commits), nil
+}
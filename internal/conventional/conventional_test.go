@@ -0,0 +1,108 @@
+// Copyright © 2020 The Stentor Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conventional
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wfscheper/stentor/config"
+	"github.com/wfscheper/stentor/fragment"
+	"github.com/wfscheper/stentor/internal/git"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		message string
+		want    Message
+		wantOK  bool
+	}{
+		{"feat: add the foo feature", Message{Type: "feat", Description: "add the foo feature"}, true},
+		{
+			"fix(parser): handle empty input\n\nSome details.\n\nRefs: #12, #13\nCloses #14",
+			Message{Type: "fix", Scope: "parser", Description: "handle empty input", Issues: []string{"12", "13", "14"}},
+			true,
+		},
+		{
+			"fix: handle empty input\n\nRefs: PROJ-123, 7\nCloses: https://github.com/o/r/issues/12\nRefs: 8,#9 10a",
+			Message{Type: "fix", Description: "handle empty input", Issues: []string{"PROJ-123", "7", "8", "9"}},
+			true,
+		},
+		{
+			"fix: handle empty input\n\nRefs: JIRA-7 proj-8 PROJ-9a\nCloses: https://jira.example.com/browse/PROJ-10",
+			Message{Type: "fix", Description: "handle empty input", Issues: []string{"JIRA-7"}},
+			true,
+		},
+		{"Feat!: drop support for foo", Message{Type: "feat", Description: "drop support for foo", Breaking: true}, true},
+		{
+			"refactor: rename the config file\n\nBREAKING CHANGE: stentor.toml is now config.toml",
+			Message{
+				Type:           "refactor",
+				Description:    "rename the config file",
+				Breaking:       true,
+				BreakingChange: "stentor.toml is now config.toml",
+			},
+			true,
+		},
+		{"Merge pull request #1 from myname/branch", Message{}, false},
+		{"feat:no space", Message{}, false},
+		{"feat(: unbalanced scope", Message{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			got, ok := Parse(git.Commit{Message: tt.message})
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFragments(t *testing.T) {
+	commits := []git.Commit{
+		{Hash: "e", Message: "docs: fix a typo"},
+		{Hash: "d", Message: "feat(api)!: remove the v1 api\n\nBREAKING CHANGE: use the v2 api instead.\nRefs: #3"},
+		{Hash: "c", Message: "fix: handle empty input\n\nCloses: #2, #5"},
+		{Hash: "b", Message: "Merge branch 'main'"},
+		{Hash: "a", Message: "feat: add the foo feature"},
+	}
+
+	cfg := config.Commits{
+		Types:           map[string]string{"feat": "feature", "fix": "fix"},
+		BreakingSection: "change",
+	}
+
+	assert.Equal(t, []fragment.Fragment{
+		{Section: "feature", Text: "add the foo feature", Provenance: commitProvenance("a")},
+		{
+			Section:    "fix",
+			Issue:      "2",
+			Issues:     []string{"2", "5"},
+			Text:       "handle empty input",
+			Provenance: commitProvenance("c"),
+		},
+		{
+			Section:    "change",
+			Issue:      "3",
+			Issues:     []string{"3"},
+			Text:       "remove the v1 api\n\nuse the v2 api instead.",
+			Provenance: commitProvenance("d"),
+		},
+	}, Fragments(commits, cfg))
+}
+
+func commitProvenance(hash string) fragment.Provenance {
+	return fragment.Provenance{Source: fragment.SourceCommits, Ref: hash}
+}
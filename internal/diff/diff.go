@@ -0,0 +1,69 @@
+// Copyright © 2020 The Stentor Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package diff produces line-based unified diffs.
+package diff
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// context is the number of unchanged lines shown around each change.
+const context = 3
+
+// noNewline marks a last line without a line ending, as diff does.
+const noNewline = "\n\\ No newline at end of file\n"
+
+// Unified returns a unified diff that turns a into b,
+// or an empty string if a and b are equal.
+//
+// The names oldName and newName are used in the diff header.
+func Unified(oldName, newName string, a, b []byte) string {
+	if bytes.Equal(a, b) {
+		return ""
+	}
+
+	// writing to a buffer cannot fail
+	d, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(string(a)),
+		B:        splitLines(string(b)),
+		FromFile: oldName,
+		ToFile:   newName,
+		Context:  context,
+	})
+
+	return d
+}
+
+// splitLines splits s into lines, keeping the line endings.
+//
+// A last line without a line ending is followed by the noNewline marker,
+// so it differs from the same line with one.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	lines := strings.SplitAfter(s, "\n")
+	if last := lines[len(lines)-1]; last == "" {
+		lines = lines[:len(lines)-1]
+	} else {
+		lines[len(lines)-1] = last + noNewline
+	}
+
+	return lines
+}
@@ -0,0 +1,153 @@
+// Copyright © 2020 The Stentor Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"pgregory.net/rapid"
+)
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{"equal", "a\nb\n", "a\nb\n", ""},
+		{
+			"insert at start",
+			"a\nb\nc\nd\ne\n",
+			"new\na\nb\nc\nd\ne\n",
+			"--- old\n+++ new\n@@ -1,3 +1,4 @@\n+new\n a\n b\n c\n",
+		},
+		{
+			"change in middle",
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			"1\n2\n3\n4\nfive\n6\n7\n8\n9\n",
+			"--- old\n+++ new\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			"separate hunks",
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			"one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ntwelve\n",
+			"--- old\n+++ new\n" +
+				"@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n" +
+				"@@ -9,4 +9,4 @@\n 9\n 10\n 11\n-12\n+twelve\n",
+		},
+		{
+			"from empty",
+			"",
+			"a\n",
+			"--- old\n+++ new\n@@ -0,0 +1 @@\n+a\n",
+		},
+		{
+			"to empty",
+			"a\nb\n",
+			"",
+			"--- old\n+++ new\n@@ -1,2 +0,0 @@\n-a\n-b\n",
+		},
+		{
+			"merged hunks",
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n",
+			"one\n2\n3\n4\n5\n6\n7\nseven\n8\n9\n10\n11\n",
+			"--- old\n+++ new\n" +
+				"@@ -1,10 +1,11 @@\n-1\n+one\n 2\n 3\n 4\n 5\n 6\n 7\n+seven\n 8\n 9\n 10\n",
+		},
+		{
+			"hunks split past twice the context",
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n",
+			"one\n2\n3\n4\n5\n6\n7\n8\n9\nnine\n10\n11\n",
+			"--- old\n+++ new\n" +
+				"@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n" +
+				"@@ -7,5 +7,6 @@\n 7\n 8\n 9\n+nine\n 10\n 11\n",
+		},
+		{
+			"newline added at end",
+			"a\nb",
+			"a\nb\n",
+			"--- old\n+++ new\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+		{
+			"no newline at end",
+			"a\nb",
+			"a\nc",
+			"--- old\n+++ new\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+c\n\\ No newline at end of file\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Unified("old", "new", []byte(tt.a), []byte(tt.b)))
+		})
+	}
+}
+
+func TestUnified_apply(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		genText := rapid.Custom(func(t *rapid.T) string {
+			lines := rapid.SliceOf(rapid.SampledFrom([]string{"a\n", "b\n", "c\n", "d\n"})).Draw(t, "lines")
+			return strings.Join(lines, "")
+		})
+		a, b := genText.Draw(t, "a"), genText.Draw(t, "b")
+
+		if got := apply(t, a, Unified("a", "b", []byte(a), []byte(b))); got != b {
+			t.Fatalf("applying the diff of %q and %q returned %q", a, b, got)
+		}
+	})
+}
+
+// apply applies the unified diff d to a.
+func apply(t *rapid.T, a, d string) string {
+	if d == "" {
+		return a
+	}
+
+	oldLines := splitLines(a)
+	var result []string
+	pos := 0
+	for _, line := range splitLines(d)[2:] {
+		switch line[0] {
+		case '@':
+			// @@ -start[,count] +start[,count] @@
+			fields := strings.Fields(line)
+			oldRange := strings.Split(fields[1][1:], ",")
+			start, err := strconv.Atoi(oldRange[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(oldRange) == 1 || oldRange[1] != "0" {
+				start--
+			}
+			result = append(result, oldLines[pos:start]...)
+			pos = start
+		case ' ', '-':
+			if oldLines[pos] != line[1:] {
+				t.Fatalf("line %d is %q, diff expected %q", pos+1, oldLines[pos], line[1:])
+			}
+			if line[0] == ' ' {
+				result = append(result, line[1:])
+			}
+			pos++
+		case '+':
+			result = append(result, line[1:])
+		}
+	}
+
+	return strings.Join(append(result, oldLines[pos:]...), "")
+}
@@ -0,0 +1,359 @@
+// Copyright © 2020 The Stentor Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package git provides a thin wrapper around the git command-line tool.
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// Repo is a git working tree.
+type Repo struct {
+	// Dir is the directory git is run in.
+	Dir string
+	// Env is the environment git is run with.
+	// If nil, git inherits the current process's environment.
+	Env []string
+}
+
+// Commit is a single git commit.
+type Commit struct {
+	// Hash is the full commit hash.
+	Hash string
+	// Message is the raw commit message.
+	Message string
+}
+
+// Trailer is a key-value pair from the trailer block of a commit message,
+// such as "Signed-off-by: A U Thor <author@example.com>".
+type Trailer struct {
+	Key   string
+	Value string
+}
+
+var (
+	// trailerRE matches "Token: value" and "Token #value" trailers.
+	// Tokens may not contain whitespace, except for the conventional "BREAKING CHANGE".
+	trailerRE   = regexp.MustCompile(`^(BREAKING CHANGE|[A-Za-z0-9][A-Za-z0-9-]*)(?::[ \t]+|[ \t]+(#))(.*)$`)
+	paragraphRE = regexp.MustCompile(`\n[ \t]*\n`)
+	// scpLikeRE matches scp-like remotes, eg. git@github.com:myname/myrepo.git
+	scpLikeRE = regexp.MustCompile(`^(?:[^@/]+@)?([^:/]+):(.+)$`)
+)
+
+// Subject returns the first line of the commit message.
+func (c Commit) Subject() string {
+	subject, _, _ := strings.Cut(strings.TrimSpace(c.Message), "\n")
+	return strings.TrimSpace(subject)
+}
+
+// Body returns the commit message without the subject and trailers.
+func (c Commit) Body() string {
+	paragraphs := splitParagraphs(c.Message)
+	if len(paragraphs) < 2 {
+		return ""
+	}
+
+	paragraphs = paragraphs[1:]
+	if _, ok := parseTrailers(paragraphs[len(paragraphs)-1]); ok {
+		paragraphs = paragraphs[:len(paragraphs)-1]
+	}
+
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// Trailers returns the trailers of the commit message.
+//
+// Trailers are read from the last paragraph of the message,
+// and only if every line in that paragraph is a trailer or a continuation of one.
+func (c Commit) Trailers() []Trailer {
+	paragraphs := splitParagraphs(c.Message)
+	if len(paragraphs) < 2 {
+		return nil
+	}
+
+	trailers, _ := parseTrailers(paragraphs[len(paragraphs)-1])
+	return trailers
+}
+
+// AddedFiles returns the files added since the merge base of base and HEAD.
+//
+// If committed is true, only files added by commits up to HEAD are returned.
+// Otherwise, the working tree is compared to the merge base,
+// and untracked files that are not ignored are included.
+// The returned names are relative to r.Dir, and limited to paths.
+func (r Repo) AddedFiles(base string, committed bool, paths ...string) ([]string, error) {
+	mergeBase, err := r.run("merge-base", base, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"diff", "--name-only", "--no-renames", "--diff-filter=A", "--relative", mergeBase}
+	if committed {
+		args = append(args, "HEAD")
+	}
+
+	out, err := r.run(append(append(args, "--"), paths...)...)
+	if err != nil {
+		return nil, err
+	}
+	files := splitLines(out)
+
+	if !committed {
+		out, err := r.run(append([]string{"ls-files", "--others", "--exclude-standard", "--"}, paths...)...)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, splitLines(out)...)
+	}
+
+	return files, nil
+}
+
+// RemoteURL returns the URL of the named remote.
+func (r Repo) RemoteURL(name string) (string, error) {
+	return r.run("remote", "get-url", name)
+}
+
+// BrowseURL converts a git remote URL into the https URL of the repository's web page.
+//
+// It understands https, ssh, git, and scp-like remotes,
+// such as git@github.com:myname/myrepo.git.
+func BrowseURL(remote string) (string, error) {
+	var host, repoPath string
+	if m := scpLikeRE.FindStringSubmatch(remote); m != nil && !strings.Contains(remote, "://") {
+		host, repoPath = m[1], m[2]
+	} else {
+		u, err := url.Parse(remote)
+		if err != nil {
+			return "", err
+		}
+
+		switch u.Scheme {
+		case "http", "https":
+			// keep the port of web URLs, since that is where the web page is served
+			u.User = nil
+			u.Path = strings.TrimSuffix(strings.TrimSuffix(u.Path, "/"), ".git")
+			u.RawQuery, u.Fragment = "", ""
+			return u.String(), nil
+		case "ssh", "git", "git+ssh":
+			host, repoPath = u.Hostname(), u.Path
+		default:
+			return "", fmt.Errorf("unsupported remote URL %q", remote)
+		}
+	}
+
+	repoPath = strings.TrimSuffix(strings.Trim(repoPath, "/"), ".git")
+	if host == "" || repoPath == "" {
+		return "", errors.New("remote URL has no host or path")
+	}
+
+	return "https://" + host + "/" + repoPath, nil
+}
+
+// CurrentBranch returns the name of the checked out branch,
+// or "HEAD" if the working tree is in a detached HEAD state.
+func (r Repo) CurrentBranch() (string, error) {
+	return r.run("rev-parse", "--abbrev-ref", "HEAD")
+}
+
+// HasHead returns true if HEAD points to a commit.
+//
+// It returns false, rather than an error, if Dir is not in a git repository,
+// or if HEAD is unborn, as it is before the first commit.
+func (r Repo) HasHead() (bool, error) {
+	_, err := r.run("rev-parse", "--verify", "--quiet", "HEAD")
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 1:
+		// --quiet exits with 1, and prints nothing, if HEAD is unborn
+		return false, nil
+	case strings.Contains(err.Error(), "not a git repository"):
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// Tags returns the names of the tags reachable from commit.
+func (r Repo) Tags(commit string) ([]string, error) {
+	out, err := r.run("tag", "--merged", commit)
+	if err != nil {
+		return nil, err
+	}
+
+	return splitLines(out), nil
+}
+
+// Log returns the commits in revRange, newest first.
+func (r Repo) Log(revRange string) ([]Commit, error) {
+	out, err := r.run("log", "--format=%H%x00%B%x00", revRange, "--")
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []Commit
+	fields := strings.Split(out, "\x00")
+	for i := 0; i+1 < len(fields); i += 2 {
+		commits = append(commits, Commit{
+			Hash:    strings.TrimSpace(fields[i]),
+			Message: strings.TrimSpace(fields[i+1]),
+		})
+	}
+
+	return commits, nil
+}
+
+// Status returns the paths of the changed and untracked files matching pathspecs,
+// relative to the top of the working tree.
+func (r Repo) Status(pathspecs ...string) ([]string, error) {
+	args := append([]string{"status", "--porcelain", "-z", "--untracked-files=all", "--"}, pathspecs...)
+	// the output is not trimmed, as the status codes may start with a space
+	out, err := r.output(args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	entries := strings.Split(out, "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+
+		paths = append(paths, entry[3:])
+		if entry[0] == 'R' || entry[0] == 'C' {
+			// skip the source of the rename or copy
+			i++
+		}
+	}
+
+	return paths, nil
+}
+
+// TrackedFiles returns the files in paths that are tracked by git.
+func (r Repo) TrackedFiles(paths ...string) ([]string, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	out, err := r.run(append([]string{"ls-files", "--"}, paths...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return splitLines(out), nil
+}
+
+// Add stages all changes to paths, including removals.
+func (r Repo) Add(paths ...string) error {
+	_, err := r.run(append([]string{"add", "--all", "--"}, paths...)...)
+	return err
+}
+
+// CommitStaged commits the staged changes with message.
+func (r Repo) CommitStaged(message string) error {
+	_, err := r.run("commit", "--quiet", "--cleanup=whitespace", "--message", message)
+	return err
+}
+
+// TagExists returns true if the tag name exists.
+func (r Repo) TagExists(name string) (bool, error) {
+	_, err := r.run("rev-parse", "--verify", "--quiet", "refs/tags/"+name)
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 1:
+		// --quiet exits with 1, and prints nothing, if the ref does not exist
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// Tag creates an annotated tag name for HEAD with message.
+func (r Repo) Tag(name, message string) error {
+	_, err := r.run("tag", "--annotate", "--cleanup=whitespace", "--message", message, name)
+	return err
+}
+
+// run runs git with args and returns its trimmed output.
+func (r Repo) run(args ...string) (string, error) {
+	out, err := r.output(args...)
+	return strings.TrimSpace(out), err
+}
+
+// output runs git with args and returns its output.
+func (r Repo) output(args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.Dir
+	cmd.Env = r.Env
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+
+	return stdout.String(), nil
+}
+
+func parseTrailers(paragraph string) ([]Trailer, bool) {
+	var trailers []Trailer
+	for _, line := range strings.Split(paragraph, "\n") {
+		if line != "" && (line[0] == ' ' || line[0] == '\t') && len(trailers) > 0 {
+			// continuation of the previous trailer
+			trailers[len(trailers)-1].Value += " " + strings.TrimSpace(line)
+			continue
+		}
+
+		m := trailerRE.FindStringSubmatch(line)
+		if m == nil {
+			return nil, false
+		}
+		trailers = append(trailers, Trailer{Key: m[1], Value: m[2] + strings.TrimSpace(m[3])})
+	}
+
+	return trailers, len(trailers) > 0
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+func splitParagraphs(msg string) []string {
+	var paragraphs []string
+	for _, p := range paragraphRE.Split(strings.TrimSpace(msg), -1) {
+		if p = strings.TrimSpace(p); p != "" {
+			paragraphs = append(paragraphs, p)
+		}
+	}
+	return paragraphs
+}
@@ -0,0 +1,203 @@
+// Copyright © 2020 The Stentor Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package semver parses and compares semantic versions.
+package semver
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Version is a semantic version, as described by https://semver.org/spec/v2.0.0.html.
+type Version struct {
+	// Prefix is an optional "v" in front of the version.
+	Prefix string
+	Major  uint64
+	Minor  uint64
+	Patch  uint64
+	// Prerelease is the dot-separated prerelease identifiers, without the leading "-".
+	Prerelease string
+	// Build is the dot-separated build metadata, without the leading "+".
+	Build string
+}
+
+// Level is how much of a version changes in a release.
+type Level int
+
+// Bump levels, from least to most significant.
+const (
+	Patch Level = iota + 1
+	Minor
+	Major
+)
+
+var levelNames = map[Level]string{Patch: "patch", Minor: "minor", Major: "major"}
+
+// ParseLevel returns the Level named s.
+func ParseLevel(s string) (Level, error) {
+	for l, name := range levelNames {
+		if s == name {
+			return l, nil
+		}
+	}
+
+	return 0, fmt.Errorf("invalid bump level %q: must be one of major, minor, or patch", s)
+}
+
+// String returns the name of the level.
+func (l Level) String() string {
+	if name, ok := levelNames[l]; ok {
+		return name
+	}
+	return fmt.Sprintf("Level(%d)", int(l))
+}
+
+var versionRE = regexp.MustCompile(`^(v?)(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+	`(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?` +
+	`(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+
+// Parse parses s into a Version.
+//
+// A leading "v" is allowed, and preserved in the Prefix.
+func Parse(s string) (Version, error) {
+	m := versionRE.FindStringSubmatch(s)
+	if m == nil {
+		return Version{}, fmt.Errorf("invalid semantic version %q", s)
+	}
+
+	v := Version{Prefix: m[1], Prerelease: m[5], Build: m[6]}
+	for i, p := range []*uint64{&v.Major, &v.Minor, &v.Patch} {
+		n, err := strconv.ParseUint(m[i+2], 10, 64)
+		if err != nil {
+			return Version{}, fmt.Errorf("invalid semantic version %q: %w", s, errors.Unwrap(err))
+		}
+		*p = n
+	}
+
+	return v, nil
+}
+
+// String returns the version in its canonical form, including the prefix.
+func (v Version) String() string {
+	s := fmt.Sprintf("%s%d.%d.%d", v.Prefix, v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// Compare returns -1, 0, or 1 if v has lower, equal, or higher precedence than o.
+//
+// The prefix and build metadata do not affect precedence.
+func (v Version) Compare(o Version) int {
+	for _, c := range [][2]uint64{{v.Major, o.Major}, {v.Minor, o.Minor}, {v.Patch, o.Patch}} {
+		if c[0] != c[1] {
+			if c[0] < c[1] {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	return comparePrerelease(v.Prerelease, o.Prerelease)
+}
+
+func comparePrerelease(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		// a release has higher precedence than a prerelease
+		return 1
+	case b == "":
+		return -1
+	}
+
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if c := compareIdentifier(as[i], bs[i]); c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case len(as) < len(bs):
+		return -1
+	case len(as) > len(bs):
+		return 1
+	default:
+		return 0
+	}
+}
+
+func compareIdentifier(a, b string) int {
+	an, aErr := strconv.ParseUint(a, 10, 64)
+	bn, bErr := strconv.ParseUint(b, 10, 64)
+	switch {
+	case aErr == nil && bErr == nil:
+		switch {
+		case an < bn:
+			return -1
+		case an > bn:
+			return 1
+		default:
+			return 0
+		}
+	case aErr == nil:
+		// numeric identifiers have lower precedence than alphanumeric ones
+		return -1
+	case bErr == nil:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+// Bump returns the version that follows v after a release with changes of the given level.
+//
+// Before 1.0.0, breaking changes only bump the minor version.
+// A prerelease is released as its own version if the level does not require a higher one,
+// so bumping the patch of 1.0.0-rc.1 returns 1.0.0.
+// The prefix is preserved, and the prerelease and build metadata are dropped.
+func (v Version) Bump(level Level) Version {
+	if v.Major == 0 && level == Major {
+		level = Minor
+	}
+
+	next := Version{Prefix: v.Prefix, Major: v.Major, Minor: v.Minor, Patch: v.Patch}
+	pre := v.Prerelease != ""
+	switch level {
+	case Major:
+		if !pre || v.Minor != 0 || v.Patch != 0 {
+			next.Major, next.Minor, next.Patch = v.Major+1, 0, 0
+		}
+	case Minor:
+		if !pre || v.Patch != 0 {
+			next.Minor, next.Patch = v.Minor+1, 0
+		}
+	default:
+		if !pre {
+			next.Patch = v.Patch + 1
+		}
+	}
+
+	return next
+}
@@ -0,0 +1,139 @@
+// Copyright © 2020 The Stentor Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package semver
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"pgregory.net/rapid"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		s         string
+		want      Version
+		wantError string
+	}{
+		{"1.2.3", Version{Major: 1, Minor: 2, Patch: 3}, ""},
+		{"v0.1.0", Version{Prefix: "v", Minor: 1}, ""},
+		{"v1.0.0-rc.1+build.5", Version{Prefix: "v", Major: 1, Prerelease: "rc.1", Build: "build.5"}, ""},
+		{"1.0.0+20200102", Version{Major: 1, Build: "20200102"}, ""},
+		{"1.0", Version{}, `invalid semantic version "1.0"`},
+		{"01.0.0", Version{}, `invalid semantic version "01.0.0"`},
+		{"1.0.0-01", Version{}, `invalid semantic version "1.0.0-01"`},
+		{"V1.0.0", Version{}, `invalid semantic version "V1.0.0"`},
+		{"2e808ef", Version{}, `invalid semantic version "2e808ef"`},
+		{"99999999999999999999.0.0", Version{}, `invalid semantic version "99999999999999999999.0.0": value out of range`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := Parse(tt.s)
+			if tt.wantError != "" {
+				assert.EqualError(t, err, tt.wantError)
+			} else if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, got)
+				assert.Equal(t, tt.s, got.String())
+			}
+		})
+	}
+}
+
+func TestVersion_Compare(t *testing.T) {
+	// ordered by precedence, from https://semver.org/spec/v2.0.0.html#spec-item-11
+	ordered := []string{
+		"0.9.0",
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"v1.0.0",
+		"1.0.1",
+		"1.1.0",
+		"1.10.0",
+		"2.0.0",
+	}
+
+	rapid.Check(t, func(t *rapid.T) {
+		perm := rapid.Permutation(ordered).Draw(t, "versions")
+
+		versions := make([]Version, len(perm))
+		for i, s := range perm {
+			v, err := Parse(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			versions[i] = v
+		}
+
+		sort.Slice(versions, func(i, j int) bool { return versions[i].Compare(versions[j]) < 0 })
+		for i, v := range versions {
+			if v.String() != ordered[i] {
+				t.Fatalf("sorted version %d is %s, want %s", i, v, ordered[i])
+			}
+		}
+	})
+
+	a, _ := Parse("v1.0.0+build.1")
+	b, _ := Parse("1.0.0+build.2")
+	assert.Equal(t, 0, a.Compare(b))
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, l := range []Level{Patch, Minor, Major} {
+		if got, err := ParseLevel(l.String()); assert.NoError(t, err) {
+			assert.Equal(t, l, got)
+		}
+	}
+
+	_, err := ParseLevel("auto")
+	assert.EqualError(t, err, `invalid bump level "auto": must be one of major, minor, or patch`)
+}
+
+func TestVersion_Bump(t *testing.T) {
+	tests := []struct {
+		version string
+		level   Level
+		want    string
+	}{
+		{"v1.2.3", Patch, "v1.2.4"},
+		{"v1.2.3", Minor, "v1.3.0"},
+		{"v1.2.3", Major, "v2.0.0"},
+		{"1.2.3+build.1", Patch, "1.2.4"},
+		{"v0.2.3", Patch, "v0.2.4"},
+		{"v0.2.3", Minor, "v0.3.0"},
+		{"v0.2.3", Major, "v0.3.0"},
+		{"v1.0.0-rc.1", Patch, "v1.0.0"},
+		{"v1.0.0-rc.1", Minor, "v1.0.0"},
+		{"v1.0.0-rc.1", Major, "v1.0.0"},
+		{"v1.1.0-rc.1", Major, "v2.0.0"},
+		{"v1.0.1-rc.1", Minor, "v1.1.0"},
+		{"v1.0.1-rc.1", Patch, "v1.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version+" "+tt.level.String(), func(t *testing.T) {
+			v, err := Parse(tt.version)
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, v.Bump(tt.level).String())
+			}
+		})
+	}
+}
@@ -16,10 +16,20 @@ package templates
 
 import (
 	"embed"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"regexp"
 	"strings"
 	"text/template"
+	"time"
+	"unicode"
+
+	"github.com/mattn/go-runewidth"
+
+	// dateFormat must find timezones on systems without a timezone database
+	_ "time/tzdata"
 )
 
 //go:embed templates
@@ -27,15 +37,40 @@ var fs embed.FS
 
 var (
 	funcMap = template.FuncMap{
-		"indent": indent,
-		"repeat": repeat,
-		"sum":    sum,
+		"dateFormat":   dateFormat,
+		"default":      defaultValue,
+		"firstLine":    firstLine,
+		"indent":       indent,
+		"join":         join,
+		"len":          length,
+		"lower":        strings.ToLower,
+		"regexReplace": regexReplace,
+		"repeat":       repeat,
+		"replace":      replace,
+		"restLines":    restLines,
+		"sum":          sum,
+		"title":        title,
+		"trim":         strings.TrimSpace,
+		"trimPrefix":   trimPrefix,
+		"trimSuffix":   trimSuffix,
+		"upper":        strings.ToUpper,
+		"wrap":         wrap,
+	}
+
+	// dateLayouts are the layouts dateFormat accepts by name.
+	dateLayouts = map[string]string{
+		"date":     "2006-01-02",
+		"datetime": "2006-01-02 15:04:05",
+		"kitchen":  time.Kitchen,
+		"rfc1123":  time.RFC1123,
+		"rfc3339":  time.RFC3339,
+		"rfc822":   time.RFC822,
 	}
 )
 
 // New returns the named template
 func New(name string) (*template.Template, error) {
-	data, err := fs.ReadFile("templates/" + name)
+	data, err := Read(name)
 	if err != nil {
 		return nil, err
 	}
@@ -43,6 +78,18 @@ func New(name string) (*template.Template, error) {
 	return template.New(name).Funcs(funcMap).Parse(string(data))
 }
 
+// Read returns the source of the named template
+func Read(name string) ([]byte, error) {
+	return fs.ReadFile("templates/" + name)
+}
+
+// SectionName returns the name of the built-in section template for hosting and markup.
+//
+// Each hosting has its own section template for each markup, named <hosting>-<markup>-section.
+func SectionName(hosting, markup string) string {
+	return hosting + "-" + markup + "-section"
+}
+
 // Parse returns the template parsed from file fn
 func Parse(fn string) (*template.Template, error) {
 	data, err := os.ReadFile(fn)
@@ -53,8 +100,88 @@ func Parse(fn string) (*template.Template, error) {
 	return template.New(filepath.Base(fn)).Funcs(funcMap).Parse(string(data))
 }
 
+// ParseText returns the template name parsed from text
+func ParseText(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(funcMap).Parse(text)
+}
+
 // template functions
 
+// dateFormat formats the time t with layout,
+// which is either the name of a layout in dateLayouts or a Go time layout.
+//
+// An optional timezone name, eg. "Europe/Berlin", may be given before t,
+// to format t in that timezone:
+// {{ .Date | dateFormat "rfc3339" "UTC" }}.
+func dateFormat(layout string, args ...interface{}) (string, error) {
+	var zone string
+	switch len(args) {
+	case 1:
+	case 2:
+		var ok bool
+		if zone, ok = args[0].(string); !ok {
+			return "", fmt.Errorf("dateFormat: timezone must be a string, not %T", args[0])
+		}
+	default:
+		return "", fmt.Errorf("dateFormat: wrong number of arguments: want layout, [timezone,] and time")
+	}
+
+	t, ok := args[len(args)-1].(time.Time)
+	if !ok {
+		return "", fmt.Errorf("dateFormat: cannot format %T", args[len(args)-1])
+	}
+
+	if zone != "" {
+		loc, err := time.LoadLocation(zone)
+		if err != nil {
+			return "", fmt.Errorf("dateFormat: %w", err)
+		}
+		t = t.In(loc)
+	}
+
+	if named, ok := dateLayouts[layout]; ok {
+		layout = named
+	}
+
+	return t.Format(layout), nil
+}
+
+// defaultValue returns value, or def if value is empty.
+//
+// Empty values are nil, false, 0, and empty strings, slices, and maps.
+func defaultValue(def, value interface{}) interface{} {
+	if value == nil {
+		return def
+	}
+
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		if v.Len() == 0 {
+			return def
+		}
+	default:
+		if v.IsZero() {
+			return def
+		}
+	}
+
+	return value
+}
+
+// firstLine returns the first line of s.
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
+
+// restLines returns the lines of s after the first one,
+// without the blank lines that separate them from the first line.
+func restLines(s string) string {
+	_, rest, _ := strings.Cut(s, "\n")
+	return strings.TrimLeft(rest, "\n")
+}
+
 // indent pads every line in s after the first with n spaces.
 //
 // This transforms:
@@ -69,11 +196,63 @@ func indent(n int, s string) string {
 	return strings.Join(lines, "\n")
 }
 
+// join concatenates the elements of list, separated by sep.
+//
+// The elements are formatted like the template would print them,
+// so list may be a slice of any type, eg. .Issues or .Links.
+func join(sep string, list interface{}) (string, error) {
+	v := reflect.ValueOf(list)
+	if v.Kind() != reflect.Array && v.Kind() != reflect.Slice {
+		return "", fmt.Errorf("join: cannot join %T", list)
+	}
+
+	elems := make([]string, v.Len())
+	for i := range elems {
+		elems[i] = fmt.Sprint(v.Index(i).Interface())
+	}
+	return strings.Join(elems, sep), nil
+}
+
+// length returns the display width of a string,
+// or the number of elements of an array, channel, map, or slice.
+//
+// It replaces the built-in len function,
+// so that reStructuredText underlines match titles with wide or combining characters.
+func length(item interface{}) (int, error) {
+	if s, ok := item.(string); ok {
+		return runewidth.StringWidth(s), nil
+	}
+
+	v := reflect.ValueOf(item)
+	switch v.Kind() {
+	case reflect.Array, reflect.Chan, reflect.Map, reflect.Slice:
+		return v.Len(), nil
+	default:
+		return 0, fmt.Errorf("len of type %T", item)
+	}
+}
+
+// regexReplace returns s with every match of the regular expression pattern replaced by repl.
+//
+// Inside repl, $1 or ${name} stand for the text of the submatches.
+func regexReplace(pattern, repl, s string) (string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", fmt.Errorf("regexReplace: %w", err)
+	}
+	return re.ReplaceAllString(s, repl), nil
+}
+
 // repeat returns the string s repeated n times.
 func repeat(n int, s string) string {
 	return strings.Repeat(s, n)
 }
 
+// replace returns s with every instance of old replaced by new.
+func replace(old, new, s string) string {
+	return strings.ReplaceAll(s, old, new)
+}
+
 // sum returns the sum of its arguments
 func sum(ns ...int) (i int) {
 	for _, n := range ns {
@@ -81,3 +260,70 @@ func sum(ns ...int) (i int) {
 	}
 	return i
 }
+
+// title returns s with the first letter of each word in upper case.
+func title(s string) string {
+	runes := []rune(s)
+	for i, r := range runes {
+		if i == 0 || unicode.IsSpace(runes[i-1]) || runes[i-1] == '-' {
+			runes[i] = unicode.ToTitle(r)
+		}
+	}
+	return string(runes)
+}
+
+// trimPrefix returns s without the leading prefix.
+func trimPrefix(prefix, s string) string {
+	return strings.TrimPrefix(s, prefix)
+}
+
+// trimSuffix returns s without the trailing suffix.
+func trimSuffix(suffix, s string) string {
+	return strings.TrimSuffix(s, suffix)
+}
+
+// wrap breaks the lines of s into lines at most width columns wide, at spaces.
+//
+// Words wider than width are not broken,
+// and the existing line breaks and indentation of s are kept.
+func wrap(width int, s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = wrapLine(width, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// wrapLine wraps a single line for wrap.
+func wrapLine(width int, line string) string {
+	if runewidth.StringWidth(line) <= width {
+		return line
+	}
+
+	trimmed := strings.TrimLeft(line, " \t")
+	prefix := line[:len(line)-len(trimmed)]
+
+	var (
+		b     strings.Builder
+		col   int
+		start = true
+	)
+	for _, word := range strings.Fields(trimmed) {
+		w := runewidth.StringWidth(word)
+		switch {
+		case start:
+			b.WriteString(prefix)
+			col = runewidth.StringWidth(prefix)
+		case col+1+w > width:
+			b.WriteString("\n" + prefix)
+			col = runewidth.StringWidth(prefix)
+		default:
+			b.WriteString(" ")
+			col++
+		}
+		b.WriteString(word)
+		col += w
+		start = false
+	}
+	return b.String()
+}
@@ -19,19 +19,26 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/wfscheper/stentor"
 )
 
 func TestLoad(t *testing.T) {
 	tests := []struct {
 		name string
 	}{
+		{"github-asciidoc-section"},
 		{"github-markdown-section"},
 		{"github-rst-section"},
+		{"gitlab-asciidoc-section"},
 		{"gitlab-markdown-section"},
 		{"gitlab-rst-section"},
+		{"asciidoc-header"},
+		{"markdown-header"},
+		{"rst-header"},
 	}
 
 	t.Parallel()
@@ -43,6 +50,38 @@ func TestLoad(t *testing.T) {
 	}
 }
 
+func TestLoad_sections(t *testing.T) {
+	hostings := []string{
+		stentor.HostingAzure, stentor.HostingBitbucket, stentor.HostingCustom, stentor.HostingForgejo,
+		stentor.HostingGitea, stentor.HostingGithub, stentor.HostingGitlab,
+	}
+
+	t.Parallel()
+	for _, hosting := range hostings {
+		for _, markup := range []string{stentor.MarkupAsciiDoc, stentor.MarkupMD, stentor.MarkupRST} {
+			name := SectionName(hosting, markup)
+			t.Run(name, func(t *testing.T) {
+				_, err := New(name)
+				assert.NoError(t, err)
+			})
+		}
+	}
+}
+
+func TestRead(t *testing.T) {
+	if data, err := Read("github-markdown-section"); assert.NoError(t, err) {
+		assert.Contains(t, string(data), "{{ .Header }} [{{ .Version }}]")
+	}
+
+	_, err := Read("notexist")
+	assert.Error(t, err)
+}
+
+func TestSectionName(t *testing.T) {
+	assert.Equal(t, "github-markdown-section", SectionName("github", "markdown"))
+	assert.Equal(t, "bitbucket-rst-section", SectionName("bitbucket", "rst"))
+}
+
 func TestNew_error(t *testing.T) {
 	_, err := New("notexist")
 	require.Error(t, err)
@@ -55,6 +94,8 @@ const customTemplate = `Custom template.
 {{ "The next two lines\nshould be indented\ntwo spaces." | indent 2 }}
 
 {{ sum 2 3 }}
+
+{{ "a, b, c" | replace ", " "+" }}
 `
 
 func TestParse(t *testing.T) {
@@ -78,6 +119,8 @@ The next two lines
   two spaces.
 
 5
+
+a+b+c
 `
 	require.Equal(t, want, buf.String())
 }
@@ -86,3 +129,78 @@ func TestParse_error(t *testing.T) {
 	_, err := Parse("notexist")
 	require.Error(t, err)
 }
+
+func TestParseText(t *testing.T) {
+	tmpl, err := ParseText("message", `Release {{ .Version }}{{ "!" | repeat 2 }}`)
+	require.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, tmpl.Execute(buf, struct{ Version string }{"v0.2.0"}))
+	assert.Equal(t, "Release v0.2.0!!", buf.String())
+
+	_, err = ParseText("message", "{{ .Version")
+	assert.Error(t, err)
+}
+
+func TestFuncs(t *testing.T) {
+	data := map[string]interface{}{
+		"Date":   time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		"Empty":  "",
+		"Issues": []string{"1", "2", "3"},
+		"Text":   "The foo feature.\n\nIt is full of foos.\nAnd bars.",
+	}
+
+	tests := []struct {
+		name, text, want, wantError string
+	}{
+		{"lower", `{{ "Foo BAR" | lower }}`, "foo bar", ""},
+		{"upper", `{{ "Foo bar" | upper }}`, "FOO BAR", ""},
+		{"title", `{{ "bug fixes and follow-up work" | title }}`, "Bug Fixes And Follow-Up Work", ""},
+		{"trim", `{{ "  foo \n" | trim }}`, "foo", ""},
+		{"trimPrefix", `{{ "v1.0.0" | trimPrefix "v" }}`, "1.0.0", ""},
+		{"trimSuffix", `{{ "foo.md" | trimSuffix ".md" }}`, "foo", ""},
+		{"wrap", `{{ "the quick brown fox jumps" | wrap 10 }}`, "the quick\nbrown fox\njumps", ""},
+		{"wrap keeps lines and indentation", `{{ "short\n  a b c d" | wrap 6 }}`, "short\n  a b\n  c d", ""},
+		{"wrap long word", `{{ "a supercalifragilistic word" | wrap 5 }}`, "a\nsupercalifragilistic\nword", ""},
+		{"wrap wide characters", `{{ "日本 語の 文章" | wrap 10 }}`, "日本 語の\n文章", ""},
+		{"join", `{{ .Issues | join ", " }}`, "1, 2, 3", ""},
+		{"join non-list", `{{ "foo" | join ", " }}`, "", "join: cannot join string"},
+		{"replace", `{{ "a, b, c" | replace ", " "+" }}`, "a+b+c", ""},
+		{"regexReplace", `{{ "fix #12 and #34" | regexReplace "#(\\d+)" "GH-$1" }}`, "fix GH-12 and GH-34", ""},
+		{"regexReplace invalid", `{{ "foo" | regexReplace "[" "" }}`, "",
+			"regexReplace: error parsing regexp: missing closing ]: `[`"},
+		{"default", `{{ .Empty | default "none" }}`, "none", ""},
+		{"default set", `{{ "foo" | default "none" }}`, "foo", ""},
+		{"default empty list", `{{ .Missing | default "none" }}`, "none", ""},
+		{"default zero", `{{ 0 | default 7 }}`, "7", ""},
+		{"dateFormat", `{{ .Date | dateFormat "2006-01-02" }}`, "2020-01-02", ""},
+		{"dateFormat named layout", `{{ .Date | dateFormat "rfc3339" }}`, "2020-01-02T03:04:05Z", ""},
+		{"dateFormat timezone", `{{ .Date | dateFormat "datetime" "America/New_York" }}`, "2020-01-01 22:04:05", ""},
+		{"dateFormat invalid timezone", `{{ .Date | dateFormat "date" "Nowhere/Special" }}`, "",
+			"dateFormat: unknown time zone Nowhere/Special"},
+		{"dateFormat not a time", `{{ "today" | dateFormat "date" }}`, "", "dateFormat: cannot format string"},
+		{"firstLine", `{{ .Text | firstLine }}`, "The foo feature.", ""},
+		{"restLines", `{{ .Text | restLines }}`, "It is full of foos.\nAnd bars.", ""},
+		{"restLines single line", `{{ "foo" | restLines }}`, "", ""},
+		{"len ascii", `{{ len "v1.0.0" }}`, "6", ""},
+		{"len wide characters", `{{ len "リリース" }}`, "8", ""},
+		{"len combining characters", "{{ len \"Cafe\u0301\" }}", "4", ""},
+		{"len list", `{{ len .Issues }}`, "3", ""},
+		{"len invalid", `{{ len 3 }}`, "", "len of type int"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := ParseText(tt.name, tt.text)
+			require.NoError(t, err)
+
+			buf := &bytes.Buffer{}
+			err = tmpl.Execute(buf, data)
+			if tt.wantError != "" {
+				assert.ErrorContains(t, err, tt.wantError)
+			} else if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright © 2020 The Stentor Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// GitRepo is a git repository for tests.
+type GitRepo struct {
+	t *testing.T
+	// Dir is the root of the repository's working tree.
+	Dir string
+	// Env is an environment that isolates git from the user's configuration.
+	Env []string
+}
+
+// NewGitRepo initializes a git repository in dir with a main branch.
+//
+// The test is skipped if git is not installed.
+func NewGitRepo(t *testing.T, dir string) *GitRepo {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	g := &GitRepo{
+		t:   t,
+		Dir: dir,
+		Env: append(os.Environ(),
+			"GIT_CONFIG_NOSYSTEM=1",
+			"GIT_CONFIG_GLOBAL="+os.DevNull,
+			"GIT_AUTHOR_NAME=A U Thor",
+			"GIT_AUTHOR_EMAIL=author@example.com",
+			"GIT_COMMITTER_NAME=C O Mitter",
+			"GIT_COMMITTER_EMAIL=committer@example.com",
+		),
+	}
+
+	g.Git("init", "--quiet")
+	g.Git("symbolic-ref", "HEAD", "refs/heads/main")
+	return g
+}
+
+// Git runs git with args in the repository and returns its trimmed output.
+func (g *GitRepo) Git(args ...string) string {
+	g.t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = g.Dir
+	cmd.Env = g.Env
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		g.t.Fatalf("git %s failed: %v\n%s", strings.Join(args, " "), err, out)
+	}
+
+	return strings.TrimSpace(string(out))
+}
+
+// WriteFile writes data to the file name in the repository,
+// creating any missing directories.
+func (g *GitRepo) WriteFile(name, data string) {
+	g.t.Helper()
+
+	fn := filepath.Join(g.Dir, name)
+	if err := os.MkdirAll(filepath.Dir(fn), 0755); err != nil {
+		g.t.Fatal(err)
+	}
+
+	if err := os.WriteFile(fn, []byte(data), 0600); err != nil {
+		g.t.Fatal(err)
+	}
+}
+
+// Commit stages all changes and commits them with msg.
+func (g *GitRepo) Commit(msg string) {
+	g.t.Helper()
+
+	g.Git("add", "--all")
+	g.Git("commit", "--quiet", "--allow-empty", "--message", msg)
+}
@@ -37,6 +37,9 @@ type Case struct {
 	Commands    [][]string `json:"commands"`
 	Skip        bool       `json:"skip"`
 	Environ     []string   `json:"environ"`
+	// Warnings is true if the last command succeeds,
+	// but writes the messages in the stderr file.
+	Warnings bool `json:"warnings"`
 }
 
 // NewCase returns a Case.
@@ -93,6 +96,14 @@ func (c *Case) CompareError(errIn error, stderr string) {
 	}
 
 	want := string(data)
+	if c.Warnings {
+		if errIn != nil {
+			c.t.Errorf("unexpected error: %v\n%s", errIn, stderr)
+		}
+		assert.Regexp(c.t, want, stderr, "stderr did not match the expected warnings")
+		return
+	}
+
 	expectError := want != ""
 	gotError := stderr != "" && errIn != nil
 	switch {
@@ -105,6 +116,67 @@ func (c *Case) CompareError(errIn error, stderr string) {
 	}
 }
 
+// CompareFinal compares the files in the final directory of the test case
+// to the matching files rooted at dir.
+//
+// Each directory in the final directory lists all of the files expected in it,
+// so a file rooted at dir that is missing from the matching final directory,
+// like a fragment file that should have been consumed, is an error.
+func (c *Case) CompareFinal(dir string) {
+	finalPath := filepath.Join(c.rootPath, "final")
+	err := filepath.Walk(finalPath, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		localpath := p[len(finalPath):]
+		if info.IsDir() {
+			c.compareDir(filepath.Join(dir, localpath), p, strings.TrimPrefix(localpath, string(filepath.Separator)))
+			return nil
+		}
+		localpath = localpath[1:]
+
+		want, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+
+		got, err := os.ReadFile(filepath.Join(dir, localpath))
+		if err != nil {
+			c.t.Errorf("could not read %s: %v", localpath, err)
+			return nil
+		}
+
+		assert.Equal(c.t, string(want), string(got), "%s did not match the expected contents", localpath)
+		return nil
+	})
+
+	if err != nil && !os.IsNotExist(err) {
+		c.t.Fatalf("could not compare final files: %v", err)
+	}
+}
+
+// compareDir reports the files in the directory got that are not in the directory want.
+//
+// localpath is the path of the directories relative to the root of the test.
+func (c *Case) compareDir(got, want, localpath string) {
+	entries, err := os.ReadDir(got)
+	if err != nil {
+		c.t.Errorf("could not read directory %s: %v", localpath, err)
+		return
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		if _, err := os.Stat(filepath.Join(want, entry.Name())); os.IsNotExist(err) {
+			c.t.Errorf("unexpected file %s", filepath.Join(localpath, entry.Name()))
+		}
+	}
+}
+
 func (c *Case) InitialPath() string {
 	return c.initialPath
 }
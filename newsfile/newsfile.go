@@ -21,12 +21,213 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/wfscheper/stentor"
 )
 
 const (
 	readLength = 1024
 )
 
+// Entry is a single release read from a news file.
+type Entry struct {
+	// Version is the version of the release, as written in its heading.
+	Version string
+	// Heading is the markup of the release heading.
+	Heading string
+	// Body is the text of the release below the heading.
+	Body string
+}
+
+var (
+	// adocHeadingRE matches AsciiDoc section titles and captures the first word of the title,
+	// ignoring the URL of a link.
+	adocHeadingRE = regexp.MustCompile(`^={1,6}[ \t]+(?:\S*\[)?([^\]\s]+)`)
+	// mdHeadingRE matches markdown headings and captures the first word of the heading,
+	// ignoring link brackets.
+	mdHeadingRE = regexp.MustCompile(`^#{1,6}[ \t]+\[?([^\]\s]+)`)
+	// rstTitleRE captures the first word of a reStructuredText title,
+	// ignoring link markup.
+	rstTitleRE = regexp.MustCompile("^`?([^`\\s]+)")
+	// adocReleaseHeadingRE matches AsciiDoc section titles that start a release,
+	// eg. "== https://host/compare/v0.1.0...v0.2.0[v0.2.0] - 2020-01-02".
+	adocReleaseHeadingRE = regexp.MustCompile(`^={2,6}[ \t]+(?:\S*\[)?(?i:v?\d+\.\d+|unreleased)`)
+	// mdReleaseHeadingRE matches markdown headings that start a release,
+	// eg. "## [v0.2.0] - 2020-01-02".
+	mdReleaseHeadingRE = regexp.MustCompile(`^#{1,6}[ \t]+\[?(?i:v?\d+\.\d+|unreleased)`)
+	// rstReleaseTitleRE matches the title line of reStructuredText headings that start a release,
+	// eg. "`v0.2.0`_ - 2020-01-02".
+	rstReleaseTitleRE = regexp.MustCompile("^`?\\[?(?i:v?\\d+\\.\\d+|unreleased)")
+)
+
+// AddStartComment returns data with startComment inserted above the first release heading.
+//
+// If data already contains startComment, it is returned unchanged.
+// If there is no release heading, startComment is added to the end of data.
+func AddStartComment(data []byte, startComment, markup string) []byte {
+	if bytes.Contains(data, []byte(startComment)) {
+		return data
+	}
+
+	comment := strings.TrimSuffix(startComment, "\n") + "\n"
+	lines := strings.SplitAfter(string(data), "\n")
+
+	offset := 0
+	for i, line := range lines {
+		if isReleaseHeading(lines, i, markup) {
+			return []byte(string(data[:offset]) + comment + "\n" + string(data[offset:]))
+		}
+		offset += len(line)
+	}
+
+	// no release heading, so add the comment to the end, separated by a blank line
+	switch {
+	case len(data) == 0, bytes.HasSuffix(data, []byte("\n\n")):
+	case bytes.HasSuffix(data, []byte("\n")):
+		comment = "\n" + comment
+	default:
+		comment = "\n\n" + comment
+	}
+
+	return []byte(string(data) + comment)
+}
+
+// ParseEntries returns the releases below startComment in data, in the order they appear.
+//
+// Releases are expected to be separated by a "----" line,
+// or a line of three apostrophes in AsciiDoc,
+// as stentor's built-in section templates do,
+// and to start with a markup heading containing the version.
+func ParseEntries(data []byte, startComment, markup string) ([]Entry, error) {
+	idx := bytes.Index(data, []byte(startComment))
+	if idx < 0 {
+		return nil, errors.New("no start comment found")
+	}
+
+	var (
+		entries []Entry
+		chunk   []string
+		prev    string
+	)
+	addEntry := func() {
+		if e, ok := parseEntry(chunk, markup); ok {
+			entries = append(entries, e)
+		}
+		chunk = nil
+	}
+
+	separator := "----"
+	if markup == stentor.MarkupAsciiDoc {
+		separator = "'''"
+	}
+
+	lines := strings.Split(string(data[idx+len(startComment):]), "\n")
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+		// a "----" line right after text is a heading underline, not a separator
+		if line == separator && prev == "" {
+			addEntry()
+		} else {
+			chunk = append(chunk, line)
+		}
+		prev = line
+	}
+	addEntry()
+
+	return entries, nil
+}
+
+// parseEntry parses lines into an Entry.
+//
+// It returns false if lines are blank.
+func parseEntry(lines []string, markup string) (Entry, bool) {
+	// trim leading and trailing blank lines
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	if len(lines) == 0 {
+		return Entry{}, false
+	}
+
+	headingLines := 1
+	if markup == stentor.MarkupRST && len(lines) > 1 && isRSTUnderline(lines[1], runewidth.StringWidth(lines[0])) {
+		headingLines = 2
+	}
+
+	var e Entry
+	e.Heading = strings.Join(lines[:headingLines], "\n")
+
+	re := rstTitleRE
+	switch markup {
+	case stentor.MarkupAsciiDoc:
+		re = adocHeadingRE
+	case stentor.MarkupMD:
+		re = mdHeadingRE
+	}
+	if m := re.FindStringSubmatch(lines[0]); m != nil {
+		e.Version = m[1]
+	} else if fields := strings.Fields(lines[0]); len(fields) > 0 {
+		e.Version = fields[0]
+	}
+
+	body := lines[headingLines:]
+	for len(body) > 0 && body[0] == "" {
+		body = body[1:]
+	}
+	if len(body) > 0 {
+		e.Body = strings.Join(body, "\n") + "\n"
+	}
+
+	return e, true
+}
+
+// isReleaseHeading returns true if lines[i] starts a release heading.
+func isReleaseHeading(lines []string, i int, markup string) bool {
+	line := strings.TrimRight(lines[i], "\r\n")
+	switch markup {
+	case stentor.MarkupAsciiDoc:
+		return adocReleaseHeadingRE.MatchString(line)
+	case stentor.MarkupMD:
+		return mdReleaseHeadingRE.MatchString(line)
+	case stentor.MarkupRST:
+		return i+1 < len(lines) &&
+			rstReleaseTitleRE.MatchString(line) &&
+			isRSTUnderline(strings.TrimRight(lines[i+1], "\r\n"), runewidth.StringWidth(line))
+	default:
+		return false
+	}
+}
+
+// isRSTUnderline returns true if line is a valid reStructuredText underline
+// for a title n columns wide.
+func isRSTUnderline(line string, n int) bool {
+	if len(line) < n || line == "" || !strings.ContainsRune("=-~^\"'*+#:.`_", rune(line[0])) {
+		return false
+	}
+
+	return strings.Count(line, line[:1]) == len(line)
+}
+
+// ReplaceReleases returns data with everything after startComment replaced by releases.
+//
+// Everything up to and including startComment is preserved.
+func ReplaceReleases(data []byte, startComment string, releases []byte) ([]byte, error) {
+	idx := bytes.Index(data, []byte(startComment))
+	if idx < 0 {
+		return nil, errors.New("no start comment found")
+	}
+
+	idx += len(startComment)
+	return append(data[:idx:idx], releases...), nil
+}
+
 // WriteFragments Deprecated: writes the release data into the file fn.
 func WriteFragments(fn, startComment string, data []byte, keepHeader bool) error {
 	return WriteRelease(fn, startComment, data, keepHeader)
@@ -52,6 +253,27 @@ func WriteRelease(fn, startComment string, data []byte, keepHeader bool) error {
 	return nil
 }
 
+// PreviewRelease returns the contents of the file fn before and after WriteRelease adds data to it,
+// without changing fn.
+//
+// If fn does not exist, the old contents are nil.
+func PreviewRelease(fn, startComment string, data []byte, keepHeader bool) ([]byte, []byte, error) {
+	src, err := os.ReadFile(fn)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, data, nil
+		}
+		return nil, nil, err
+	}
+
+	dst := &bytes.Buffer{}
+	if err := copyIntoFile(dst, bytes.NewReader(src), []byte(startComment), data, keepHeader); err != nil {
+		return nil, nil, err
+	}
+
+	return src, dst.Bytes(), nil
+}
+
 func writeRelease(fn string, startComment, data []byte, keepHeader bool) (string, error) {
 	dst, err := os.CreateTemp(filepath.Dir(fn), "")
 	if err != nil {
@@ -63,6 +63,253 @@ func TestWriteFragments_no_comment(t *testing.T) {
 	require.EqualError(t, WriteRelease(fn, stentor.CommentMD, []byte("added data\n"), true), "no start comment found")
 }
 
+func TestPreviewRelease(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "file")
+
+	if old, updated, err := PreviewRelease(fn, stentor.CommentMD, []byte("added data\n"), true); assert.NoError(t, err) {
+		assert.Nil(t, old)
+		assert.Equal(t, "added data\n", string(updated))
+	}
+
+	data := "some text\n<!-- stentor output starts -->\nsome more text\n"
+	require.NoError(t, os.WriteFile(fn, []byte(data), 0600))
+
+	if old, updated, err := PreviewRelease(fn, stentor.CommentMD, []byte("\nadded data\n"), true); assert.NoError(t, err) {
+		assert.Equal(t, data, string(old))
+		assert.Equal(t, "some text\n<!-- stentor output starts -->\nadded data\n\nsome more text\n", string(updated))
+	}
+
+	// fn is unchanged
+	if got, err := os.ReadFile(fn); assert.NoError(t, err) {
+		assert.Equal(t, data, string(got))
+	}
+
+	_, _, err := PreviewRelease(fn, stentor.CommentRST, nil, true)
+	assert.EqualError(t, err, "no start comment found")
+}
+
+func TestAddStartComment(t *testing.T) {
+	tests := []struct {
+		name, markup, data, want string
+	}{
+		{
+			"markdown heading",
+			stentor.MarkupMD,
+			"# Changelog\n\nIntro.\n\n## [v1.0.0] - 2020-01-02\n\n- A change.\n\n## 0.1.0\n",
+			"# Changelog\n\nIntro.\n\n<!-- stentor output starts -->\n\n## [v1.0.0] - 2020-01-02\n\n- A change.\n\n## 0.1.0\n",
+		},
+		{
+			"markdown unreleased heading",
+			stentor.MarkupMD,
+			"# Changelog\n\n## [Unreleased]\n\n## [1.0.0]\n",
+			"# Changelog\n\n<!-- stentor output starts -->\n\n## [Unreleased]\n\n## [1.0.0]\n",
+		},
+		{
+			"markdown no heading",
+			stentor.MarkupMD,
+			"# Changelog\n\n## Notes\n",
+			"# Changelog\n\n## Notes\n\n<!-- stentor output starts -->\n",
+		},
+		{
+			"markdown no trailing newline",
+			stentor.MarkupMD,
+			"# Changelog",
+			"# Changelog\n\n<!-- stentor output starts -->\n",
+		},
+		{
+			"markdown empty",
+			stentor.MarkupMD,
+			"",
+			"<!-- stentor output starts -->\n",
+		},
+		{
+			"markdown existing comment",
+			stentor.MarkupMD,
+			"# Changelog\n<!-- stentor output starts -->\n## [v1.0.0]\n",
+			"# Changelog\n<!-- stentor output starts -->\n## [v1.0.0]\n",
+		},
+		{
+			"rst heading",
+			stentor.MarkupRST,
+			"Changelog\n=========\n\n`v1.0.0`_ - 2020-01-02\n======================\n\n1.0 (2019)\n----------\n",
+			"Changelog\n=========\n\n.. stentor output starts\n\n`v1.0.0`_ - 2020-01-02\n======================\n\n1.0 (2019)\n----------\n",
+		},
+		{
+			"rst non-ascii heading",
+			stentor.MarkupRST,
+			"Changelog\n=========\n\n`v1.0.0-ß`_ - 2020-01-02\n========================\n",
+			"Changelog\n=========\n\n.. stentor output starts\n\n`v1.0.0-ß`_ - 2020-01-02\n========================\n",
+		},
+		{
+			"rst short underline",
+			stentor.MarkupRST,
+			"Changelog\n=========\n\nv1.0.0\n===\n",
+			"Changelog\n=========\n\nv1.0.0\n===\n\n.. stentor output starts\n",
+		},
+		{
+			"rst title only",
+			stentor.MarkupRST,
+			"Changelog\n\nv1.0.0 is the best\n",
+			"Changelog\n\nv1.0.0 is the best\n\n.. stentor output starts\n",
+		},
+		{
+			"asciidoc heading",
+			stentor.MarkupAsciiDoc,
+			"= Changelog\n\nIntro.\n\n== https://host/compare/v0.1.0...v1.0.0[v1.0.0] - 2020-01-02\n\n* A change.\n",
+			"= Changelog\n\nIntro.\n\n// stentor output starts\n\n" +
+				"== https://host/compare/v0.1.0...v1.0.0[v1.0.0] - 2020-01-02\n\n* A change.\n",
+		},
+		{
+			"asciidoc no heading",
+			stentor.MarkupAsciiDoc,
+			"= v1.0.0\n\n== Notes\n",
+			"= v1.0.0\n\n== Notes\n\n// stentor output starts\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			startComment := stentor.CommentMD
+			switch tt.markup {
+			case stentor.MarkupAsciiDoc:
+				startComment = stentor.CommentAsciiDoc
+			case stentor.MarkupRST:
+				startComment = stentor.CommentRST
+			}
+
+			got := AddStartComment([]byte(tt.data), startComment, tt.markup)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestParseEntries(t *testing.T) {
+	tests := []struct {
+		name, markup, data string
+		want               []Entry
+		wantError          string
+	}{
+		{
+			name:   "markdown",
+			markup: stentor.MarkupMD,
+			data: "# Changelog\n\n----\n\n<!-- stentor output starts -->\n" +
+				"## [v0.2.0] - 2020-01-02\n\n### Added\n\n- A feature.\n\n[v0.2.0]: https://host/compare/v0.1.0...v0.2.0\n\n\n----\n\n\n" +
+				"## [v0.1.0] - 2020-01-01\n\nNo significant changes.\n\n\n----\n\n",
+			want: []Entry{
+				{
+					Version: "v0.2.0",
+					Heading: "## [v0.2.0] - 2020-01-02",
+					Body:    "### Added\n\n- A feature.\n\n[v0.2.0]: https://host/compare/v0.1.0...v0.2.0\n",
+				},
+				{
+					Version: "v0.1.0",
+					Heading: "## [v0.1.0] - 2020-01-01",
+					Body:    "No significant changes.\n",
+				},
+			},
+		},
+		{
+			name:   "markdown without separators",
+			markup: stentor.MarkupMD,
+			data:   "<!-- stentor output starts -->\n## 1.0.0\nSetext heading\n----\nText.\n",
+			want: []Entry{
+				{
+					Version: "1.0.0",
+					Heading: "## 1.0.0",
+					Body:    "Setext heading\n----\nText.\n",
+				},
+			},
+		},
+		{
+			name:   "rst",
+			markup: stentor.MarkupRST,
+			data: ".. stentor output starts\n\n" +
+				"`v0.2.0`_ - 2020-01-02\n======================\n\nMisc\n----\n\n- A change.\n\n\n----\n\n" +
+				"v0.1.0 - 2020-01-01\n===================\n\n----\n",
+			want: []Entry{
+				{
+					Version: "v0.2.0",
+					Heading: "`v0.2.0`_ - 2020-01-02\n======================",
+					Body:    "Misc\n----\n\n- A change.\n",
+				},
+				{
+					Version: "v0.1.0",
+					Heading: "v0.1.0 - 2020-01-01\n===================",
+				},
+			},
+		},
+		{
+			name:   "rst non-ascii",
+			markup: stentor.MarkupRST,
+			data: ".. stentor output starts\n\n" +
+				"`v1.0.0-ß`_ - 2020-01-02\n========================\n\n- A change.\n\n\n----\n",
+			want: []Entry{
+				{
+					Version: "v1.0.0-ß",
+					Heading: "`v1.0.0-ß`_ - 2020-01-02\n========================",
+					Body:    "- A change.\n",
+				},
+			},
+		},
+		{
+			name:   "asciidoc",
+			markup: stentor.MarkupAsciiDoc,
+			data: "= Changelog\n\n// stentor output starts\n\n" +
+				"== https://host/compare/v0.1.0...v0.2.0[v0.2.0] - 2020-01-02\n\n=== Added\n\n* A feature.\n\n\n'''\n\n" +
+				"== v0.1.0 - 2020-01-01\n\n----\nA listing.\n----\n\n\n'''\n\n",
+			want: []Entry{
+				{
+					Version: "v0.2.0",
+					Heading: "== https://host/compare/v0.1.0...v0.2.0[v0.2.0] - 2020-01-02",
+					Body:    "=== Added\n\n* A feature.\n",
+				},
+				{
+					Version: "v0.1.0",
+					Heading: "== v0.1.0 - 2020-01-01",
+					Body:    "----\nA listing.\n----\n",
+				},
+			},
+		},
+		{
+			name:      "no start comment",
+			markup:    stentor.MarkupMD,
+			data:      "# Changelog\n",
+			wantError: "no start comment found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			startComment := stentor.CommentMD
+			switch tt.markup {
+			case stentor.MarkupAsciiDoc:
+				startComment = stentor.CommentAsciiDoc
+			case stentor.MarkupRST:
+				startComment = stentor.CommentRST
+			}
+
+			got, err := ParseEntries([]byte(tt.data), startComment, tt.markup)
+			if tt.wantError != "" {
+				assert.EqualError(t, err, tt.wantError)
+			} else if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestReplaceReleases(t *testing.T) {
+	data := []byte("# Changelog\n\n<!-- stentor output starts -->\n## [v0.1.0]\n\nOld.\n")
+	if got, err := ReplaceReleases(data, stentor.CommentMD, []byte("\n## [v0.1.0]\n\nNew.\n")); assert.NoError(t, err) {
+		assert.Equal(t, "# Changelog\n\n<!-- stentor output starts -->\n## [v0.1.0]\n\nNew.\n", string(got))
+		// data is not modified
+		assert.Equal(t, "# Changelog\n\n<!-- stentor output starts -->\n## [v0.1.0]\n\nOld.\n", string(data))
+	}
+
+	_, err := ReplaceReleases([]byte("# Changelog\n"), stentor.CommentMD, nil)
+	assert.EqualError(t, err, "no start comment found")
+}
+
 func Test_copyIntoFile(t *testing.T) {
 	rapid.Check(t, func(t *rapid.T) {
 		nt := newsfileGen().Draw(t, "newsfile")
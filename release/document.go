@@ -0,0 +1,151 @@
+// Copyright © 2020 The Stentor Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/wfscheper/stentor"
+	"github.com/wfscheper/stentor/fragment"
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaVersion is the version of the schema of Document.
+//
+// It changes whenever a field is removed or changes meaning,
+// but not when fields are added.
+const SchemaVersion = 1
+
+// Document is the structured form of a Release, for tools that consume releases as data.
+type Document struct {
+	// SchemaVersion is the version of the schema of the document.
+	SchemaVersion int `json:"schema_version" yaml:"schema_version"`
+	// Version is the version of the release.
+	Version string `json:"version" yaml:"version"`
+	// PreviousVersion is the version before the release, or empty for the first release.
+	PreviousVersion string `json:"previous_version" yaml:"previous_version"`
+	// Date is the date of the release, formatted as YYYY-MM-DD.
+	Date string `json:"date" yaml:"date"`
+	// Repository is the URL of the project repository.
+	Repository string `json:"repository" yaml:"repository"`
+	// CompareURL is the URL of the changes between PreviousVersion and Version,
+	// or empty for the first release.
+	CompareURL string `json:"compare_url,omitempty" yaml:"compare_url,omitempty"`
+	// Sections are the sections of the release, in the configured order.
+	Sections []DocumentSection `json:"sections" yaml:"sections"`
+}
+
+// DocumentSection is a section of a Document.
+type DocumentSection struct {
+	// Title is the name of the section.
+	Title string `json:"title" yaml:"title"`
+	// Fragments are the news items in the section.
+	Fragments []DocumentFragment `json:"fragments" yaml:"fragments"`
+}
+
+// DocumentFragment is a news item of a DocumentSection.
+type DocumentFragment struct {
+	// Text is the news item, in the markup of the news file.
+	Text string `json:"text" yaml:"text"`
+	// Issues are the IDs of the issues the news item refers to.
+	Issues []string `json:"issues,omitempty" yaml:"issues,omitempty"`
+	// PullRequests are the IDs of the pull requests the news item refers to.
+	PullRequests []string `json:"pull_requests,omitempty" yaml:"pull_requests,omitempty"`
+	// Authors are the authors of the change.
+	Authors []string `json:"authors,omitempty" yaml:"authors,omitempty"`
+	// Component is the part of the project the change affects.
+	Component string `json:"component,omitempty" yaml:"component,omitempty"`
+	// Extra holds the front matter keys that stentor does not know about.
+	Extra map[string]interface{} `json:"extra,omitempty" yaml:"extra,omitempty"`
+	// Links are the resolved links of the issues and pull requests.
+	Links []DocumentLink `json:"links,omitempty" yaml:"links,omitempty"`
+}
+
+// DocumentLink is a link of a DocumentFragment.
+type DocumentLink struct {
+	// Label is the text of the link, eg. "#12".
+	Label string `json:"label" yaml:"label"`
+	// URL is the target of the link.
+	URL string `json:"url" yaml:"url"`
+}
+
+// Document returns the structured form of r.
+//
+// Links are only set if SetLinks was called first.
+func (r *Release) Document() Document {
+	d := Document{
+		SchemaVersion:   SchemaVersion,
+		Version:         r.Version,
+		PreviousVersion: r.PreviousVersion,
+		Date:            r.Date.Format("2006-01-02"),
+		Repository:      r.Repository,
+		CompareURL:      r.CompareURL,
+		Sections:        make([]DocumentSection, 0, len(r.Sections)),
+	}
+
+	for _, s := range r.Sections {
+		ds := DocumentSection{Title: s.Title, Fragments: make([]DocumentFragment, 0, len(s.Fragments))}
+		for _, f := range s.Fragments {
+			ds.Fragments = append(ds.Fragments, documentFragment(f))
+		}
+		d.Sections = append(d.Sections, ds)
+	}
+
+	return d
+}
+
+func documentFragment(f fragment.Fragment) DocumentFragment {
+	df := DocumentFragment{
+		Text:         f.Text,
+		Issues:       f.Issues,
+		PullRequests: f.PullRequests,
+		Authors:      f.Authors,
+		Component:    f.Component,
+		Extra:        f.Extra,
+	}
+
+	// fragments that only set Issue still refer to it
+	if len(df.Issues) == 0 && len(df.PullRequests) == 0 && f.Issue != "" {
+		df.Issues = []string{f.Issue}
+	}
+
+	for _, l := range f.Links {
+		df.Links = append(df.Links, DocumentLink{Label: l.Label, URL: l.URL})
+	}
+
+	return df
+}
+
+// Encode writes the Document of r to w in format,
+// which is one of stentor.FormatJSON or stentor.FormatYAML.
+func (r *Release) Encode(w io.Writer, format string) error {
+	switch format {
+	case stentor.FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r.Document())
+	case stentor.FormatYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(r.Document()); err != nil {
+			return err
+		}
+		return enc.Close()
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+}
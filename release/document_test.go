@@ -0,0 +1,168 @@
+// Copyright © 2020 The Stentor Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wfscheper/stentor/fragment"
+	"github.com/wfscheper/stentor/hosting"
+	"github.com/wfscheper/stentor/section"
+)
+
+func TestRelease_Encode(t *testing.T) {
+	r, err := New("https://github.com/myname/myrepo", "markdown", "v0.2.0", "v0.1.0")
+	require.NoError(t, err)
+
+	r.Date = time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+	r.Sections = []section.Section{
+		{
+			Fragments: []fragment.Fragment{
+				{
+					Issues:       []string{"1"},
+					PullRequests: []string{"2"},
+					Authors:      []string{"alice"},
+					Component:    "parser",
+					Extra:        map[string]interface{}{"breaking": true},
+					Text:         "The foo feature.",
+				},
+				{Issue: "3", Text: "A legacy fragment."},
+			},
+			Title: "Added",
+		},
+		{ShowAlways: true, Title: "Fixed"},
+	}
+
+	p, err := hosting.New("github", r.Repository, nil)
+	require.NoError(t, err)
+	require.NoError(t, r.SetLinks(p, nil))
+
+	tests := []struct {
+		format, want string
+	}{
+		{
+			"json",
+			`{
+  "schema_version": 1,
+  "version": "v0.2.0",
+  "previous_version": "v0.1.0",
+  "date": "2020-01-02",
+  "repository": "https://github.com/myname/myrepo",
+  "compare_url": "https://github.com/myname/myrepo/compare/v0.1.0...v0.2.0",
+  "sections": [
+    {
+      "title": "Added",
+      "fragments": [
+        {
+          "text": "The foo feature.",
+          "issues": [
+            "1"
+          ],
+          "pull_requests": [
+            "2"
+          ],
+          "authors": [
+            "alice"
+          ],
+          "component": "parser",
+          "extra": {
+            "breaking": true
+          },
+          "links": [
+            {
+              "label": "#1",
+              "url": "https://github.com/myname/myrepo/issues/1"
+            },
+            {
+              "label": "#2",
+              "url": "https://github.com/myname/myrepo/pull/2"
+            }
+          ]
+        },
+        {
+          "text": "A legacy fragment.",
+          "issues": [
+            "3"
+          ],
+          "links": [
+            {
+              "label": "#3",
+              "url": "https://github.com/myname/myrepo/issues/3"
+            }
+          ]
+        }
+      ]
+    },
+    {
+      "title": "Fixed",
+      "fragments": []
+    }
+  ]
+}
+`,
+		},
+		{
+			"yaml",
+			`schema_version: 1
+version: v0.2.0
+previous_version: v0.1.0
+date: "2020-01-02"
+repository: https://github.com/myname/myrepo
+compare_url: https://github.com/myname/myrepo/compare/v0.1.0...v0.2.0
+sections:
+  - title: Added
+    fragments:
+      - text: The foo feature.
+        issues:
+          - "1"
+        pull_requests:
+          - "2"
+        authors:
+          - alice
+        component: parser
+        extra:
+          breaking: true
+        links:
+          - label: '#1'
+            url: https://github.com/myname/myrepo/issues/1
+          - label: '#2'
+            url: https://github.com/myname/myrepo/pull/2
+      - text: A legacy fragment.
+        issues:
+          - "3"
+        links:
+          - label: '#3'
+            url: https://github.com/myname/myrepo/issues/3
+  - title: Fixed
+    fragments: []
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			if assert.NoError(t, r.Encode(buf, tt.format)) {
+				assert.Equal(t, tt.want, buf.String())
+			}
+		})
+	}
+
+	assert.EqualError(t, r.Encode(&bytes.Buffer{}, "xml"), `unknown format "xml"`)
+}
@@ -17,22 +17,32 @@ package release
 
 import (
 	"errors"
+	"fmt"
 	"net/url"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/wfscheper/stentor"
 	"github.com/wfscheper/stentor/config"
 	"github.com/wfscheper/stentor/fragment"
+	"github.com/wfscheper/stentor/hosting"
 	"github.com/wfscheper/stentor/section"
 )
 
 // Release represents the data used to generate a release entry in a stentor-managed news file.
 type Release struct {
+	// CompareURL is the URL of the changes between PreviousVersion and Version.
+	// It is empty for the first release.
+	CompareURL string
 	// Date is the date of the release.
 	Date time.Time
 	// Header is the markup character used when writing the release header.
 	Header string
+	// Hosting builds the URLs of the repository's pages on its hosting provider.
+	Hosting hosting.Provider
 	// PreviousVersion is the version before this release.
+	// It is empty for the first release.
 	PreviousVersion string
 	// Repository is the URL of the project repository.
 	Repository string
@@ -49,6 +59,8 @@ type Release struct {
 // The repo should be a parsable URL.
 func New(repo, markup, version, previousVersion string) (*Release, error) {
 	switch markup {
+	case stentor.MarkupAsciiDoc:
+		return newAsciiDoc(repo, version, previousVersion)
 	case stentor.MarkupMD:
 		return newMarkdown(repo, version, previousVersion)
 	case stentor.MarkupRST:
@@ -58,6 +70,70 @@ func New(repo, markup, version, previousVersion string) (*Release, error) {
 	}
 }
 
+// IsFirst returns true if r is the first release of the project,
+// ie. there is no previous version to compare it to.
+func (r *Release) IsFirst() bool {
+	return r.PreviousVersion == ""
+}
+
+// SetLinks resolves the compare link of the release, unless it is the first release,
+// and the links of the fragments in the release's sections.
+//
+// Issues link to the first of trackers whose pattern matches their ID,
+// and other issues and pull requests link to their pages on the hosting provider p.
+// Fragments that only set Issue link it as an issue.
+// Issues and pull requests are not linked if p has no URL for them.
+func (r *Release) SetLinks(p hosting.Provider, trackers []config.Tracker) error {
+	r.Hosting = p
+	r.CompareURL = ""
+	if !r.IsFirst() {
+		r.CompareURL = p.CompareURL(r.PreviousVersion, r.Version)
+	}
+
+	patterns := make([]*regexp.Regexp, len(trackers))
+	for i, t := range trackers {
+		re, err := regexp.Compile(t.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid tracker pattern: %w", err)
+		}
+		patterns[i] = re
+	}
+
+	issueLink := func(id string) fragment.Link {
+		for i, re := range patterns {
+			if re.MatchString(id) {
+				return fragment.Link{Label: id, URL: strings.ReplaceAll(trackers[i].URL, config.TrackerID, id)}
+			}
+		}
+		return fragment.Link{Label: "#" + id, URL: p.IssueURL(id)}
+	}
+
+	for i := range r.Sections {
+		for j := range r.Sections[i].Fragments {
+			f := &r.Sections[i].Fragments[j]
+
+			issues := f.Issues
+			if len(issues) == 0 && len(f.PullRequests) == 0 && f.Issue != "" {
+				issues = []string{f.Issue}
+			}
+
+			f.Links = nil
+			for _, id := range issues {
+				if link := issueLink(id); link.URL != "" {
+					f.Links = append(f.Links, link)
+				}
+			}
+			for _, id := range f.PullRequests {
+				if u := p.PullRequestURL(id); u != "" {
+					f.Links = append(f.Links, fragment.Link{Label: p.PullRequestLabel(id), URL: u})
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
 // SetSections populates the release's sections.
 func (r *Release) SetSections(sections []config.Section, fragments []fragment.Fragment) {
 	sectionMap := map[string]section.Section{}
@@ -108,6 +184,18 @@ func newRelease(repo, version, previousVersion string) (*Release, error) {
 	}, nil
 }
 
+// newAsciiDoc returns a Release with AsciiDoc style Header and SectionHeader.
+func newAsciiDoc(repo, version, previousVersion string) (*Release, error) {
+	r, err := newRelease(repo, version, previousVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Header = "=="
+	r.SectionHeader = "==="
+	return r, nil
+}
+
 // NewMarkdownRelease returns a Release with markdown style Header and SectionHeader.
 func newMarkdown(repo, version, previousVersion string) (*Release, error) {
 	r, err := newRelease(repo, version, previousVersion)
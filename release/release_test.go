@@ -16,13 +16,18 @@ package release
 
 import (
 	"bytes"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/wfscheper/stentor/config"
 	"github.com/wfscheper/stentor/fragment"
+	"github.com/wfscheper/stentor/hosting"
 	"github.com/wfscheper/stentor/internal/templates"
+	"github.com/wfscheper/stentor/internal/test"
 	"github.com/wfscheper/stentor/section"
 )
 
@@ -32,6 +37,38 @@ func TestSectionTemplate(t *testing.T) {
 		releaseFunc func(string, string, string) (*Release, error)
 		want        string
 	}{
+		{
+			"github-asciidoc-section",
+			newAsciiDoc,
+			"== https://host/myname/myrepo/compare/v0.1.0...v0.2.0[v0.2.0] - 2020-01-02\n" +
+				"\n" +
+				"=== Features\n" +
+				"\n" +
+				"* The foo feature.\n" +
+				"+\n" +
+				"This is an awesome feature.\n" +
+				"https://host/myname/myrepo/issues/1[#1]\n" +
+				"\n" +
+				"\n" +
+				"=== Bug Fixes\n" +
+				"\n" +
+				"* Fix the bug in foo.\n" +
+				"https://host/myname/myrepo/issues/2[#2]\n" +
+				"* Fix several bugs.\n" +
+				"https://host/myname/myrepo/issues/3[#3], https://host/myname/myrepo/issues/4[#4]\n" +
+				"* Fix the bar.\n" +
+				"https://host/myname/myrepo/issues/5[#5], https://host/myname/myrepo/pull/6[#6]\n" +
+				"* Multiple other things.\n" +
+				"\n" +
+				"\n" +
+				"=== Always Show\n" +
+				"\n" +
+				"No significant changes.\n" +
+				"\n" +
+				"\n" +
+				"'''\n" +
+				"\n",
+		},
 		{
 			"github-markdown-section",
 			newMarkdown,
@@ -49,6 +86,10 @@ func TestSectionTemplate(t *testing.T) {
 				"\n" +
 				"- Fix the bug in foo.\n" +
 				"  [#2](https://host/myname/myrepo/issues/2)\n" +
+				"- Fix several bugs.\n" +
+				"  [#3](https://host/myname/myrepo/issues/3), [#4](https://host/myname/myrepo/issues/4)\n" +
+				"- Fix the bar.\n" +
+				"  [#5](https://host/myname/myrepo/issues/5), [#6](https://host/myname/myrepo/pull/6)\n" +
 				"- Multiple other things.\n" +
 				"\n" +
 				"\n" +
@@ -83,6 +124,10 @@ func TestSectionTemplate(t *testing.T) {
 				"\n" +
 				"- Fix the bug in foo.\n" +
 				"  `#2 <https://host/myname/myrepo/issues/2>`_\n" +
+				"- Fix several bugs.\n" +
+				"  `#3 <https://host/myname/myrepo/issues/3>`_, `#4 <https://host/myname/myrepo/issues/4>`_\n" +
+				"- Fix the bar.\n" +
+				"  `#5 <https://host/myname/myrepo/issues/5>`_, `#6 <https://host/myname/myrepo/pull/6>`_\n" +
 				"- Multiple other things.\n" +
 				"\n" +
 				"\n" +
@@ -124,6 +169,17 @@ func TestSectionTemplate(t *testing.T) {
 							Issue: "2",
 							Text:  "Fix the bug in foo.",
 						},
+						{
+							Issue:  "3",
+							Issues: []string{"3", "4"},
+							Text:   "Fix several bugs.",
+						},
+						{
+							Issue:        "5",
+							Issues:       []string{"5"},
+							PullRequests: []string{"6"},
+							Text:         "Fix the bar.",
+						},
 						{
 							Text: "Multiple other things.",
 						},
@@ -136,6 +192,10 @@ func TestSectionTemplate(t *testing.T) {
 				},
 			}
 
+			p, err := hosting.New("github", r.Repository, nil)
+			require.NoError(t, err)
+			require.NoError(t, r.SetLinks(p, nil))
+
 			tmp, err := templates.New(tt.name)
 			require.NoError(t, err)
 
@@ -147,6 +207,195 @@ func TestSectionTemplate(t *testing.T) {
 	}
 }
 
+func TestSectionTemplate_hosting(t *testing.T) {
+	repositories := map[string]string{
+		"azure":     "https://dev.azure.com/myorg/myproject/_git/myrepo",
+		"bitbucket": "https://bitbucket.example.com/projects/PROJ/repos/myrepo",
+		"custom":    "https://git.example.com/myname/myrepo",
+		"forgejo":   "https://codeberg.org/myname/myrepo",
+		"gitea":     "https://gitea.example.com/myname/myrepo",
+		"github":    "https://github.com/myname/myrepo",
+		"gitlab":    "https://gitlab.com/myname/myrepo",
+	}
+	customURLs := &config.URLs{
+		Compare:           "{repository}/diff/{previous}..{version}",
+		Issue:             "{repository}/tickets/{id}",
+		PullRequest:       "{repository}/reviews/{id}",
+		PullRequestPrefix: "CR-",
+		Commit:            "{repository}/rev/{id}",
+		Tag:               "{repository}/tag/{id}",
+	}
+
+	for host, repo := range repositories {
+		for _, markup := range []string{"asciidoc", "markdown", "rst"} {
+			name := host + "-" + markup + "-section"
+			t.Run(name, func(t *testing.T) {
+				r, err := New(repo, markup, "v0.2.0", "v0.1.0")
+				require.NoError(t, err)
+
+				r.Date = time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+				r.Sections = []section.Section{
+					{
+						Fragments: []fragment.Fragment{
+							{Issues: []string{"1", "2"}, Text: "The foo feature."},
+							{PullRequests: []string{"3"}, Text: "The bar feature."},
+						},
+						Title: "Features",
+					},
+					{
+						Fragments: []fragment.Fragment{
+							{Issue: "4", Text: "Fix the bug in foo."},
+							{Text: "Multiple other things."},
+						},
+						Title: "Bug Fixes",
+					},
+				}
+
+				p, err := hosting.New(host, r.Repository, customURLs)
+				require.NoError(t, err)
+				require.NoError(t, r.SetLinks(p, nil))
+
+				tmp, err := templates.New(name)
+				require.NoError(t, err)
+
+				buf := &bytes.Buffer{}
+				require.NoError(t, tmp.Execute(buf, r))
+
+				golden := filepath.Join("testdata", name+".golden")
+				if *test.UpdateGolden {
+					require.NoError(t, os.WriteFile(golden, buf.Bytes(), 0644))
+				}
+
+				want, err := os.ReadFile(golden)
+				require.NoError(t, err)
+				assert.Equal(t, string(want), buf.String())
+			})
+		}
+	}
+}
+
+func TestRelease_SetLinks(t *testing.T) {
+	r, err := New("https://gitlab.com/myname/myrepo", "markdown", "v0.2.0", "v0.1.0")
+	require.NoError(t, err)
+
+	r.Sections = []section.Section{{
+		Fragments: []fragment.Fragment{
+			{Issues: []string{"PROJ-12", "34", "OPS-5"}, PullRequests: []string{"6"}},
+			{Issue: "7"},
+			{Text: "No links."},
+		},
+	}}
+
+	trackers := []config.Tracker{
+		{Pattern: `^PROJ-\d+$`, URL: "https://jira.example.com/browse/{id}"},
+		{Pattern: `^[A-Z]+-\d+$`, URL: "https://ops.example.com/{id}"},
+		{Pattern: `^[A-Z]+-\d+$`, URL: "https://unused.example.com/{id}"},
+	}
+	p, err := hosting.New("gitlab", r.Repository, nil)
+	require.NoError(t, err)
+
+	if assert.NoError(t, r.SetLinks(p, trackers)) {
+		assert.Equal(t, "https://gitlab.com/myname/myrepo/-/compare/v0.1.0...v0.2.0", r.CompareURL)
+		assert.Equal(t, []fragment.Link{
+			{Label: "PROJ-12", URL: "https://jira.example.com/browse/PROJ-12"},
+			{Label: "#34", URL: "https://gitlab.com/myname/myrepo/-/issues/34"},
+			{Label: "OPS-5", URL: "https://ops.example.com/OPS-5"},
+			{Label: "!6", URL: "https://gitlab.com/myname/myrepo/-/merge_requests/6"},
+		}, r.Sections[0].Fragments[0].Links)
+		assert.Equal(t, []fragment.Link{
+			{Label: "#7", URL: "https://gitlab.com/myname/myrepo/-/issues/7"},
+		}, r.Sections[0].Fragments[1].Links)
+		assert.Empty(t, r.Sections[0].Fragments[2].Links)
+	}
+
+	trackers[0].Pattern = "[A-Z"
+	assert.EqualError(t, r.SetLinks(p, trackers),
+		"invalid tracker pattern: error parsing regexp: missing closing ]: `[A-Z`")
+}
+
+func TestRelease_SetLinks_noIssueURL(t *testing.T) {
+	r, err := New("https://bitbucket.example.com/projects/PROJ/repos/myrepo", "markdown", "v0.2.0", "v0.1.0")
+	require.NoError(t, err)
+
+	r.Sections = []section.Section{{
+		Fragments: []fragment.Fragment{{Issues: []string{"12", "PROJ-3"}, PullRequests: []string{"4"}}},
+	}}
+
+	trackers := []config.Tracker{{Pattern: `^PROJ-\d+$`, URL: "https://jira.example.com/browse/{id}"}}
+	p, err := hosting.New("bitbucket", r.Repository, nil)
+	require.NoError(t, err)
+
+	if assert.NoError(t, r.SetLinks(p, trackers)) {
+		assert.Equal(t, []fragment.Link{
+			{Label: "PROJ-3", URL: "https://jira.example.com/browse/PROJ-3"},
+			{Label: "#4", URL: "https://bitbucket.example.com/projects/PROJ/repos/myrepo/pull-requests/4"},
+		}, r.Sections[0].Fragments[0].Links)
+	}
+}
+
+func TestRelease_SetLinks_first(t *testing.T) {
+	r, err := New("https://github.com/myname/myrepo", "markdown", "v0.1.0", "")
+	require.NoError(t, err)
+	assert.True(t, r.IsFirst())
+
+	p, err := hosting.New("github", r.Repository, nil)
+	require.NoError(t, err)
+
+	if assert.NoError(t, r.SetLinks(p, nil)) {
+		assert.Empty(t, r.CompareURL)
+	}
+}
+
+func TestSectionTemplate_first(t *testing.T) {
+	tests := []struct {
+		markup, want string
+	}{
+		{"asciidoc", "== https://github.com/myname/myrepo/releases/tag/v0.1.0[v0.1.0] - 2020-01-02\n"},
+		{"markdown", "[v0.1.0]: https://github.com/myname/myrepo/releases/tag/v0.1.0\n"},
+		{"rst", ".. _v0.1.0: https://github.com/myname/myrepo/releases/tag/v0.1.0\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.markup, func(t *testing.T) {
+			r, err := New("https://github.com/myname/myrepo", tt.markup, "v0.1.0", "")
+			require.NoError(t, err)
+
+			r.Date = time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+			p, err := hosting.New("github", r.Repository, nil)
+			require.NoError(t, err)
+			require.NoError(t, r.SetLinks(p, nil))
+
+			tmp, err := templates.New("github-" + tt.markup + "-section")
+			require.NoError(t, err)
+
+			buf := &bytes.Buffer{}
+			if assert.NoError(t, tmp.Execute(buf, r)) {
+				assert.Contains(t, buf.String(), tt.want)
+				assert.NotContains(t, buf.String(), "compare")
+			}
+		})
+	}
+}
+
+func TestSectionTemplate_rstWidth(t *testing.T) {
+	r, err := New("https://github.com/myname/myrepo", "rst", "v0.2.0", "v0.1.0")
+	require.NoError(t, err)
+
+	r.Date = time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+	r.Sections = []section.Section{{ShowAlways: true, Title: "修正"}}
+	p, err := hosting.New("github", r.Repository, nil)
+	require.NoError(t, err)
+	require.NoError(t, r.SetLinks(p, nil))
+
+	tmp, err := templates.New("github-rst-section")
+	require.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	if assert.NoError(t, tmp.Execute(buf, r)) {
+		assert.Contains(t, buf.String(), "\n修正\n----\n")
+	}
+}
+
 func Test_newRelease(t *testing.T) {
 	tests := []struct {
 		repo, want, wantError string
@@ -18,18 +18,39 @@ package stentor
 // Supported hosting platforms.
 const (
 	//
-	HostingGithub = "github"
-	HostingGitlab = "gitlab"
+	HostingAzure     = "azure"
+	HostingBitbucket = "bitbucket"
+	HostingForgejo   = "forgejo"
+	HostingGitea     = "gitea"
+	HostingGithub    = "github"
+	HostingGitlab    = "gitlab"
+	// HostingCustom builds URLs from the patterns in the urls table of the config file.
+	HostingCustom = "custom"
 )
 
 // Supported markup formats.
 const (
-	MarkupMD  = "markdown"
-	MarkupRST = "rst"
+	MarkupAsciiDoc = "asciidoc"
+	MarkupMD       = "markdown"
+	MarkupRST      = "rst"
+)
+
+// Structured formats a release can be written in.
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// Levels of the version bump a section causes.
+const (
+	BumpMajor = "major"
+	BumpMinor = "minor"
+	BumpPatch = "patch"
 )
 
 // Comment styles that separate the news file's header from the releases.
 const (
-	CommentMD  = "<!-- stentor output starts -->"
-	CommentRST = ".. stentor output starts\n"
+	CommentAsciiDoc = "// stentor output starts\n"
+	CommentMD       = "<!-- stentor output starts -->"
+	CommentRST      = ".. stentor output starts\n"
 )